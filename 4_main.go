@@ -1,37 +1,120 @@
 package main
 
 import (
-	"bufio"
+	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
+
+	"github.com/parsaakbari1209/interpreter/pkg/uni"
 )
 
 func main() {
-	scope := NewScope(nil)
-	if len(os.Args) < 2 {
-		scanner := bufio.NewScanner(os.Stdin)
-		fmt.Println("Uni Version 0.1.0")
-		for {
-			fmt.Print(">> ")
-			scanner.Scan()
-			sourceCode := scanner.Text()
-			lexer := NewLexer(sourceCode)
-			parser := NewParser(lexer)
-			evaluator := NewEvaluator(parser)
-			if evaluated := evaluator.Eval(scope); evaluated != nil {
-				fmt.Println(evaluated)
-			} else {
-				fmt.Println("")
-			}
+	var eval string
+	var ast bool
+	var profile bool
+	var precision int
+	var sandbox bool
+	flag.StringVar(&eval, "e", "", "evaluate the given source code and exit")
+	flag.StringVar(&eval, "eval", "", "evaluate the given source code and exit")
+	flag.BoolVar(&ast, "ast", false, "print the parsed syntax tree instead of evaluating")
+	flag.IntVar(&precision, "precision", 0, "round the floats shown to this many significant digits")
+	flag.BoolVar(&sandbox, "sandbox", false, "disable the builtins that reach files, the environment, or the network")
+	flag.BoolVar(&profile, "profile", false, "print how many times each kind of node was evaluated, and for how long")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: uni [-ast] [-profile] [-precision digits] [-sandbox] [-e source] [file.uni]\n       uni test [-sandbox] file.uni")
+		flag.PrintDefaults()
+	}
+	testing, args := testCommand(os.Args[1:])
+	flag.CommandLine.Parse(args)
+	args = flag.Args()
+	if !testing {
+		testing, args = testCommand(args)
+	}
+	if testing && len(args) != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	sourceCode := eval
+	if sourceCode == "" && len(args) > 0 {
+		content, err := os.ReadFile(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+		sourceCode = string(content)
+	}
+
+	switch {
+	case ast:
+		if err := printAST(sourceCode, os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+	case testing:
+		if !runTests(sourceCode, os.Stdout, sandbox) {
+			os.Exit(1)
+		}
+	case eval != "" || len(args) > 0:
+		evaluator := uni.NewEvaluatorFor(sourceCode, os.Stdout)
+		evaluator.SetPrecision(precision)
+		evaluator.SetSandbox(sandbox)
+		var p *uni.Profile
+		if profile {
+			p = uni.NewProfile()
+			evaluator.SetProfile(p)
+		}
+		result := evaluator.Eval(uni.NewGlobalScope())
+		if p != nil {
+			p.Report(os.Stderr)
 		}
+		if _, ok := result.(error); ok {
+			os.Exit(1) // the evaluator has written the error to stderr
+		}
+	default:
+		opts := DefaultREPLOptions()
+		opts.Precision = precision
+		opts.Sandbox = sandbox
+		RunREPL(os.Stdin, os.Stdout, opts)
+	}
+}
+
+// testCommand reports whether the arguments start with the test subcommand, and returns
+// the arguments after it, so that the flags can come before or after it.
+func testCommand(args []string) (bool, []string) {
+	if len(args) > 0 && args[0] == "test" {
+		return true, args[1:]
+	}
+	return false, args
+}
+
+// runTests runs a script in which failing asserts don't stop the program, then prints each
+// failure and a summary. It reports whether every assertion passed.
+func runTests(sourceCode string, out io.Writer, sandbox bool) bool {
+	evaluator := uni.NewEvaluatorFor(sourceCode, out)
+	evaluator.SetSandbox(sandbox)
+	evaluator.SetErrorOutput(io.Discard) // reported below, with the failures
+	results := &uni.TestResults{}
+	evaluator.SetTestResults(results)
+	err, failed := evaluator.Eval(uni.NewGlobalScope()).(error)
+	for _, failure := range results.Failures {
+		fmt.Fprintf(out, "FAIL: %s\n", failure)
+	}
+	if failed {
+		fmt.Fprintf(out, "ERROR: %s\n", err)
+	}
+	fmt.Fprintf(out, "%d passed, %d failed\n", results.Passed, results.Failed)
+	return !failed && results.Failed == 0
+}
+
+func printAST(sourceCode string, out io.Writer) error {
+	lexer := uni.NewLexer(sourceCode)
+	parser := uni.NewParser(lexer)
+	for statement := range parser.Parse() {
+		fmt.Fprintln(out, uni.Dump(statement))
 	}
-	sourceCode, err := os.ReadFile(os.Args[1])
-	if err != nil {
-		log.Fatal(err)
+	if errs := parser.Errors(); len(errs) > 0 {
+		return fmt.Errorf("parse error: %w", errs[0])
 	}
-	lexer := NewLexer(string(sourceCode))
-	parser := NewParser(lexer)
-	evaluator := NewEvaluator(parser)
-	evaluator.Eval(scope)
+	return nil
 }
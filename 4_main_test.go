@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunTests(t *testing.T) {
+	out := &bytes.Buffer{}
+	assert.True(t, runTests("assert(1 < 2)\nassert_eq(1 + 1, 2.0)", out, false))
+	assert.Equal(t, "2 passed, 0 failed\n", out.String())
+
+	out.Reset()
+	sourceCode := "assert(true)\nassert(1 > 2, \"one is bigger\")\nassert_eq([1, 2], [1, 2])\nassert_eq(\"a\", \"b\")\nprintln(\"done\")"
+	assert.False(t, runTests(sourceCode, out, false))
+	assert.Equal(t, "done\nFAIL: assertion failed: one is bigger\nFAIL: assertion failed: a != b\n2 passed, 2 failed\n", out.String())
+
+	out.Reset()
+	assert.False(t, runTests("assert(true)\nif a = 1 { }", out, false))
+	assert.Equal(t, "ERROR: 2:6: unexpected = in condition, did you mean ==?\n1 passed, 0 failed\n", out.String())
+
+	out.Reset()
+	assert.False(t, runTests("assert(true)\nenv(\"HOME\")", out, true))
+	assert.Equal(t, "ERROR: 2:1: runtime error: env is disabled in the sandbox\n1 passed, 0 failed\n", out.String())
+}
+
+func TestTestCommand(t *testing.T) {
+	test, args := testCommand([]string{"test", "-sandbox", "f.uni"})
+	assert.True(t, test)
+	assert.Equal(t, []string{"-sandbox", "f.uni"}, args)
+
+	test, args = testCommand([]string{"-sandbox", "f.uni"})
+	assert.False(t, test)
+	assert.Equal(t, []string{"-sandbox", "f.uni"}, args)
+}
+
+func TestPrintAST(t *testing.T) {
+	out := &bytes.Buffer{}
+	assert.NoError(t, printAST("var a = 1 + 2 * 3\nprintln(a)", out))
+	assert.Equal(t, "var a = (1 + (2 * 3))\nprintln(a)\n", out.String())
+
+	out.Reset()
+	assert.Error(t, printAST("1 + )", out))
+}
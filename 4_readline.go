@@ -0,0 +1,345 @@
+// The REPL reads its input through a line reader. When stdin is a terminal, it is switched
+// to raw mode while a line is typed, so the line can be edited in place, previous entries
+// can be recalled with the arrow keys, and Tab completes the identifier under the cursor.
+// Anything else (pipes, files, tests) is simply read line by line.
+
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+const maxHistory = 1000
+
+var errInterrupt = errors.New("interrupt")
+
+type lineReader interface {
+	ReadLine(prompt string) (string, error)
+	// Input is what the lines are read from, which the program reads its input from too,
+	// so neither buffers away what the other is waiting for.
+	Input() *bufio.Reader
+}
+
+// completer returns the candidates that can replace the given partial identifier.
+type completer func(word string) []string
+
+func newLineReader(in io.Reader, out io.Writer, complete completer) lineReader {
+	if file, ok := in.(*os.File); ok && isTerminal(file) {
+		if _, err := exec.LookPath("stty"); err == nil {
+			if r, err := newTerminalReader(file, out, historyPath(), complete); err == nil {
+				return r
+			}
+		}
+	}
+	return newPlainReader(in, out)
+}
+
+// *****************
+// ** PlainReader **
+// *****************
+
+type plainReader struct {
+	reader *bufio.Reader
+	out    io.Writer
+}
+
+func newPlainReader(in io.Reader, out io.Writer) *plainReader {
+	return &plainReader{reader: bufio.NewReader(in), out: out}
+}
+
+func (r *plainReader) ReadLine(prompt string) (string, error) {
+	fmt.Fprint(r.out, prompt)
+	line, err := r.reader.ReadString('\n')
+	if err == io.EOF && line == "" {
+		return "", io.EOF
+	}
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r"), nil
+}
+
+func (r *plainReader) Input() *bufio.Reader {
+	return r.reader
+}
+
+// ********************
+// ** TerminalReader **
+// ********************
+
+type terminalReader struct {
+	file        *os.File
+	state       string // the terminal's own settings, which the program runs with
+	reader      *bufio.Reader
+	out         io.Writer
+	history     []string
+	historyPath string
+	complete    completer
+}
+
+func newTerminalReader(file *os.File, out io.Writer, historyPath string, complete completer) (*terminalReader, error) {
+	state, err := stty(file, "-g")
+	if err != nil {
+		return nil, err
+	}
+	r := &terminalReader{
+		file:        file,
+		state:       strings.TrimSpace(state),
+		reader:      bufio.NewReader(file),
+		out:         out,
+		historyPath: historyPath,
+		complete:    complete,
+	}
+	r.loadHistory()
+	return r, nil
+}
+
+// ReadLine switches the terminal to raw mode only while the line is edited, so that what
+// the line runs, input calls included, sees the terminal as it was.
+func (r *terminalReader) ReadLine(prompt string) (string, error) {
+	if r.file != nil {
+		if _, err := stty(r.file, "raw", "-echo"); err != nil {
+			return "", err
+		}
+		defer stty(r.file, r.state)
+	}
+	return r.edit(prompt)
+}
+
+func (r *terminalReader) Input() *bufio.Reader {
+	return r.reader
+}
+
+func (r *terminalReader) edit(prompt string) (string, error) {
+	var line []rune
+	cursor := 0
+	historyIndex := len(r.history)
+	draft := ""
+	redraw := func() {
+		fmt.Fprintf(r.out, "\r%s%s\x1b[K", prompt, string(line))
+		if back := len(line) - cursor; back > 0 {
+			fmt.Fprintf(r.out, "\x1b[%dD", back)
+		}
+	}
+	recall := func(index int) {
+		if historyIndex == len(r.history) {
+			draft = string(line)
+		}
+		historyIndex = index
+		if historyIndex == len(r.history) {
+			line = []rune(draft)
+		} else {
+			line = []rune(r.history[historyIndex])
+		}
+		cursor = len(line)
+	}
+	redraw()
+	for {
+		key, _, err := r.reader.ReadRune()
+		if err != nil {
+			return "", err
+		}
+		switch key {
+		case '\r', '\n':
+			fmt.Fprint(r.out, "\r\n")
+			r.addHistory(string(line))
+			return string(line), nil
+		case 3: // ctrl-c
+			fmt.Fprint(r.out, "^C")
+			return "", errInterrupt
+		case 4: // ctrl-d
+			if len(line) == 0 {
+				return "", io.EOF
+			}
+			if cursor < len(line) {
+				line = append(line[:cursor], line[cursor+1:]...)
+			}
+		case 1: // ctrl-a
+			cursor = 0
+		case 5: // ctrl-e
+			cursor = len(line)
+		case 11: // ctrl-k
+			line = line[:cursor]
+		case 21: // ctrl-u
+			line = line[cursor:]
+			cursor = 0
+		case '\t':
+			if r.complete == nil {
+				continue
+			}
+			start := cursor
+			for start > 0 && isIdentifierRune(line[start-1]) {
+				start--
+			}
+			word := line[start:cursor]
+			if len(word) == 0 {
+				continue
+			}
+			candidates := r.complete(string(word))
+			if len(candidates) == 0 {
+				continue
+			}
+			prefix := []rune(commonPrefix(candidates))
+			if len(prefix) > len(word) {
+				rest := prefix[len(word):]
+				line = append(line[:cursor], append(rest, line[cursor:]...)...)
+				cursor += len(rest)
+			} else if len(candidates) > 1 {
+				fmt.Fprintf(r.out, "\r\n%s\r\n", strings.Join(candidates, "  "))
+			}
+		case 8, 127: // backspace
+			if cursor > 0 {
+				line = append(line[:cursor-1], line[cursor:]...)
+				cursor--
+			}
+		case 27: // escape sequence
+			switch r.readEscape() {
+			case "[A":
+				if historyIndex > 0 {
+					recall(historyIndex - 1)
+				}
+			case "[B":
+				if historyIndex < len(r.history) {
+					recall(historyIndex + 1)
+				}
+			case "[C":
+				if cursor < len(line) {
+					cursor++
+				}
+			case "[D":
+				if cursor > 0 {
+					cursor--
+				}
+			case "[H", "[1~", "OH":
+				cursor = 0
+			case "[F", "[4~", "OF":
+				cursor = len(line)
+			case "[3~":
+				if cursor < len(line) {
+					line = append(line[:cursor], line[cursor+1:]...)
+				}
+			}
+		default:
+			if key < ' ' {
+				continue
+			}
+			line = append(line[:cursor], append([]rune{key}, line[cursor:]...)...)
+			cursor++
+		}
+		redraw()
+	}
+}
+
+func (r *terminalReader) readEscape() string {
+	sequence := ""
+	for {
+		key, _, err := r.reader.ReadRune()
+		if err != nil {
+			return sequence
+		}
+		sequence += string(key)
+		if len(sequence) > 1 && (key == '~' || key >= 'A' && key <= 'Z') {
+			return sequence
+		}
+		if len(sequence) == 1 && key != '[' && key != 'O' {
+			return sequence
+		}
+	}
+}
+
+func (r *terminalReader) loadHistory() {
+	if r.historyPath == "" {
+		return
+	}
+	content, err := os.ReadFile(r.historyPath)
+	if err != nil {
+		return
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		if line != "" {
+			r.history = append(r.history, line)
+		}
+	}
+	if len(r.history) > maxHistory {
+		r.history = r.history[len(r.history)-maxHistory:]
+		r.saveHistory()
+	}
+}
+
+func (r *terminalReader) addHistory(line string) {
+	if strings.TrimSpace(line) == "" {
+		return
+	}
+	if len(r.history) > 0 && r.history[len(r.history)-1] == line {
+		return
+	}
+	r.history = append(r.history, line)
+	if len(r.history) > maxHistory {
+		// rewrite the file rather than append to it, so it stays as long as the history
+		r.history = r.history[1:]
+		r.saveHistory()
+		return
+	}
+	if r.historyPath == "" {
+		return
+	}
+	file, err := os.OpenFile(r.historyPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	fmt.Fprintln(file, line)
+}
+
+func (r *terminalReader) saveHistory() {
+	if r.historyPath == "" {
+		return
+	}
+	content := strings.Join(r.history, "\n") + "\n"
+	os.WriteFile(r.historyPath, []byte(content), 0600)
+}
+
+func isIdentifierRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
+
+func commonPrefix(words []string) string {
+	prefix := []rune(words[0])
+	for _, word := range words[1:] {
+		w := []rune(word)
+		n := 0
+		for n < len(prefix) && n < len(w) && prefix[n] == w[n] {
+			n++
+		}
+		prefix = prefix[:n]
+	}
+	return string(prefix)
+}
+
+func historyPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".uni_history")
+}
+
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func stty(file *os.File, args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = file
+	out, err := cmd.Output()
+	return string(out), err
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTerminalReader(t *testing.T) {
+	tt := []struct {
+		name    string
+		history []string
+		in      string
+		want    []string
+	}{
+		{
+			name: "plain lines",
+			in:   "var a = 1\rprintln(a)\r",
+			want: []string{"var a = 1", "println(a)"},
+		},
+		{
+			name: "backspace",
+			in:   "abd\x7fc\r",
+			want: []string{"abc"},
+		},
+		{
+			name: "cursor movement",
+			in:   "ac\x1b[Db\x1b[C!\r",
+			want: []string{"abc!"},
+		},
+		{
+			name: "home and kill",
+			in:   "bc\x01a\rxyz\x01\x0b\r",
+			want: []string{"abc", ""},
+		},
+		{
+			name:    "history up and down",
+			history: []string{"first", "second"},
+			in:      "\x1b[A\r\x1b[A\x1b[A\r\x1b[A\x1b[Bdraft\x1b[A\x1b[B\r",
+			want:    []string{"second", "first", "draft"},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			r := &terminalReader{
+				reader:  bufio.NewReader(strings.NewReader(tc.in)),
+				out:     &bytes.Buffer{},
+				history: tc.history,
+			}
+			for _, want := range tc.want {
+				got, err := r.ReadLine(">> ")
+				assert.NoError(t, err)
+				assert.Equal(t, want, got)
+			}
+			_, err := r.ReadLine(">> ")
+			assert.Equal(t, io.EOF, err)
+		})
+	}
+}
+
+func TestTerminalReaderCompletion(t *testing.T) {
+	out := &bytes.Buffer{}
+	r := &terminalReader{
+		reader: bufio.NewReader(strings.NewReader("println(tot\t)\rtr\t\r")),
+		out:    out,
+		complete: func(word string) []string {
+			candidates := make([]string, 0)
+			for _, name := range []string{"total", "triple", "true"} {
+				if strings.HasPrefix(name, word) {
+					candidates = append(candidates, name)
+				}
+			}
+			return candidates
+		},
+	}
+	got, _ := r.ReadLine(">> ")
+	assert.Equal(t, "println(total)", got)
+	got, _ = r.ReadLine(">> ")
+	assert.Equal(t, "tr", got)
+	assert.Contains(t, out.String(), "triple  true")
+}
+
+func TestTerminalReaderHistoryFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+	assert.NoError(t, os.WriteFile(path, []byte("var a = 1\n"), 0600))
+
+	r := &terminalReader{
+		reader:      bufio.NewReader(strings.NewReader("\x1b[A\r\x1b[A\x1b[A\r\x04")),
+		out:         &bytes.Buffer{},
+		historyPath: path,
+	}
+	r.loadHistory()
+	got, _ := r.ReadLine(">> ")
+	assert.Equal(t, "var a = 1", got)
+	got, _ = r.ReadLine(">> ")
+	assert.Equal(t, "var a = 1", got)
+	_, err := r.ReadLine(">> ")
+	assert.Equal(t, io.EOF, err)
+
+	r = &terminalReader{
+		reader:      bufio.NewReader(strings.NewReader("a + 1\r")),
+		out:         &bytes.Buffer{},
+		historyPath: path,
+	}
+	r.loadHistory()
+	r.ReadLine(">> ")
+	content, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "var a = 1\na + 1\n", string(content))
+}
+
+func TestTerminalReaderTrimsHistoryFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+	lines := make([]string, maxHistory+10)
+	for i := range lines {
+		lines[i] = fmt.Sprint("line ", i)
+	}
+	assert.NoError(t, os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0600))
+
+	r := &terminalReader{
+		reader:      bufio.NewReader(strings.NewReader("last\r")),
+		out:         &bytes.Buffer{},
+		historyPath: path,
+	}
+	r.loadHistory()
+	content, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, strings.Join(lines[10:], "\n")+"\n", string(content))
+
+	r.ReadLine(">> ")
+	content, err = os.ReadFile(path)
+	assert.NoError(t, err)
+	want := append(lines[11:], "last")
+	assert.Equal(t, strings.Join(want, "\n")+"\n", string(content))
+	assert.Equal(t, want, r.history)
+}
@@ -0,0 +1,207 @@
+// The REPL (read-eval-print loop) reads one line at a time, evaluates it against a scope
+// that lives as long as the session, and echoes the result. A line that leaves a block, a
+// call, an array, or a string open is held until the lines after it close it. Lines starting
+// with a dot are commands to the REPL itself rather than source code.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+
+	"github.com/parsaakbari1209/interpreter/pkg/uni"
+)
+
+type REPLOptions struct {
+	Prompt             string
+	ContinuationPrompt string
+	Banner             string
+	Precision          int
+	Sandbox            bool
+	ErrorOutput        io.Writer
+}
+
+func DefaultREPLOptions() REPLOptions {
+	return REPLOptions{
+		Prompt:             ">> ",
+		ContinuationPrompt: ".. ",
+		Banner:             "Uni Version " + uni.Version,
+		ErrorOutput:        os.Stderr,
+	}
+}
+
+// RunREPL reads source code from in until it's exhausted, and writes the results to out.
+// An empty banner is not printed, a non-zero precision rounds the floats shown, like
+// Evaluator.SetPrecision, and Sandbox disables the builtins Evaluator.SetSandbox does.
+// Errors are written to the error output, or to out without one. The continuation prompt
+// is shown while incomplete input is being held, and Ctrl-C throws that input away.
+func RunREPL(in io.Reader, out io.Writer, opts REPLOptions) {
+	errOut := opts.ErrorOutput
+	if errOut == nil {
+		errOut = out
+	}
+	scope := uni.NewGlobalScope()
+	reader := newLineReader(in, out, func(word string) []string {
+		return completeNames(scope, word)
+	})
+	if opts.Banner != "" {
+		fmt.Fprintln(out, opts.Banner)
+	}
+	interrupted := false
+	var pending []string
+	for {
+		prompt := opts.Prompt
+		if len(pending) > 0 {
+			prompt = opts.ContinuationPrompt
+		}
+		line, err := reader.ReadLine(prompt)
+		if err == errInterrupt && len(pending) > 0 {
+			fmt.Fprintln(out)
+			pending = nil
+			continue
+		}
+		if err == errInterrupt && !interrupted {
+			fmt.Fprintln(out, "\n(press Ctrl-C again to exit)")
+			interrupted = true
+			continue
+		}
+		if err != nil && len(pending) == 0 {
+			fmt.Fprintln(out)
+			return
+		}
+		interrupted = false
+		if command := strings.TrimSpace(line); err == nil && len(pending) == 0 && strings.HasPrefix(command, ".") {
+			switch command {
+			case ".exit":
+				return
+			case ".clear":
+				scope = uni.NewGlobalScope()
+			case ".vars":
+				printVariables(scope, out, opts.Precision)
+			case ".help":
+				printCommands(out)
+			default:
+				fmt.Fprintf(errOut, "unknown command %s, see .help\n", command)
+			}
+			continue
+		}
+		if err == nil {
+			pending = append(pending, line)
+		}
+		sourceCode := strings.Join(pending, "\n")
+		if err == nil && isIncomplete(sourceCode) {
+			continue
+		}
+		// the input is complete, or it ended, in which case evaluating it reports the error
+		pending = nil
+		evaluated := runInterruptible(sourceCode, scope, reader.Input(), out, errOut, opts)
+		if _, failed := evaluated.(error); evaluated != nil && !failed {
+			fmt.Fprintln(out, uni.InspectPrecision(evaluated, opts.Precision))
+		}
+	}
+}
+
+func getREPLCommands() map[string]string {
+	return map[string]string{
+		".exit":  "leave the REPL",
+		".clear": "forget every variable and function defined so far",
+		".vars":  "list the variables defined so far, with their values",
+		".help":  "list these commands",
+	}
+}
+
+func printCommands(out io.Writer) {
+	commands := getREPLCommands()
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(out, "%-7s %s\n", name, commands[name])
+	}
+}
+
+// printVariables lists the variables of the top-level scope, leaving out the functions,
+// which include the prelude.
+func printVariables(scope *uni.Scope, out io.Writer, precision int) {
+	variables := scope.Variables()
+	names := make([]string, 0, len(variables))
+	for name := range variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	// unlike print, quote strings, so they read the way they'd be written
+	for _, name := range names {
+		fmt.Fprintf(out, "%s = %s\n", name, uni.InspectSource(variables[name], precision))
+	}
+}
+
+// isIncomplete reports whether the source code leaves a {, (, or [ open, or ends inside a
+// string or block comment, so that more lines are needed to finish the statement.
+func isIncomplete(sourceCode string) bool {
+	depth := 0
+	unterminated := false
+	for token := range uni.NewLexer(sourceCode).Lex() {
+		switch token.Type {
+		case uni.LCURLY, uni.LPAREN, uni.LBRACKET:
+			depth++
+		case uni.RCURLY, uni.RPAREN, uni.RBRACKET:
+			depth--
+		case uni.ILLEGAL:
+			unterminated = strings.HasPrefix(token.Value, `"`) || token.Value == "/*"
+		}
+	}
+	return depth > 0 || unterminated
+}
+
+// runInterruptible evaluates the source until it finishes or the process receives an
+// interrupt, which cancels the evaluation instead of killing the process. The program's
+// input calls read from in.
+func runInterruptible(sourceCode string, scope *uni.Scope, in io.Reader, out, errOut io.Writer, opts REPLOptions) any {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	defer signal.Stop(interrupts)
+	go func() {
+		select {
+		case <-interrupts:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	evaluator := uni.NewEvaluatorFor(sourceCode, out)
+	evaluator.SetInput(in)
+	evaluator.SetErrorOutput(errOut)
+	evaluator.SetPrecision(opts.Precision)
+	evaluator.SetSandbox(opts.Sandbox)
+	return evaluator.EvalWithContext(ctx, scope)
+}
+
+// completeNames lists the variables, functions, builtins, and keywords starting with prefix.
+func completeNames(scope *uni.Scope, prefix string) []string {
+	names := make(map[string]bool)
+	for name := range scope.Snapshot() {
+		names[name] = true
+	}
+	for _, name := range uni.BuiltinNames() {
+		names[name] = true
+	}
+	for _, name := range uni.KeywordNames() {
+		names[name] = true
+	}
+	candidates := make([]string, 0)
+	for name := range names {
+		if name != "" && strings.HasPrefix(name, prefix) {
+			candidates = append(candidates, name)
+		}
+	}
+	sort.Strings(candidates)
+	return candidates
+}
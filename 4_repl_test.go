@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/parsaakbari1209/interpreter/pkg/uni"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestREPL(t *testing.T) {
+	tt := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			name: "banner",
+			in:   "",
+			want: "Uni Version 0.1.0\n>> \n",
+		},
+		{
+			name: "empty lines",
+			in:   "\n  \n# comment\n",
+			want: "Uni Version 0.1.0\n>> >> >> >> \n",
+		},
+		{
+			name: "nil result",
+			in:   "var a = 1\n",
+			want: "Uni Version 0.1.0\n>> >> \n",
+		},
+		{
+			name: "expression result",
+			in:   "var a = 1\na + 1\n",
+			want: "Uni Version 0.1.0\n>> >> 2\n>> \n",
+		},
+		{
+			name: "print output",
+			in:   "println(\"hi\")\n",
+			want: "Uni Version 0.1.0\n>> hi\n>> \n",
+		},
+		{
+			name: "input",
+			in:   "var x = input()\nhello\nprintln(\"got\", x)\n",
+			want: "Uni Version 0.1.0\n>> >> got hello\n>> \n",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			RunREPL(strings.NewReader(tc.in), out, DefaultREPLOptions())
+			assert.Equal(t, tc.want, out.String())
+		})
+	}
+}
+
+func TestREPLOptions(t *testing.T) {
+	out := &bytes.Buffer{}
+	opts := REPLOptions{Prompt: "uni> ", ContinuationPrompt: "...> "}
+	RunREPL(strings.NewReader("var a = 2\na * 3\n"), out, opts)
+	assert.Equal(t, "uni> uni> 6\nuni> \n", out.String())
+
+	out.Reset()
+	opts.Banner = "Welcome!"
+	RunREPL(strings.NewReader(""), out, opts)
+	assert.Equal(t, "Welcome!\nuni> \n", out.String())
+
+	out.Reset()
+	opts = REPLOptions{Prompt: "> ", Precision: 3}
+	RunREPL(strings.NewReader("var m = {\"a\": [1, 2.5]}\nm\n\"text\"\n"), out, opts)
+	assert.Equal(t, "> > {\"a\": [1, 2.5]}\n> text\n> \n", out.String())
+
+	out.Reset()
+	RunREPL(strings.NewReader("var a = 0.1 + 0.2\na\na == 0.3\n"), out, opts)
+	assert.Equal(t, "> > 0.3\n> false\n> \n", out.String())
+
+	out.Reset()
+	errOut := &bytes.Buffer{}
+	opts = REPLOptions{Prompt: "> ", ErrorOutput: errOut}
+	RunREPL(strings.NewReader("print(1)\nassert(false)\n"), out, opts)
+	assert.Equal(t, "> 1> > \n", out.String())
+	assert.Equal(t, "1:1: runtime error: assertion failed\n", errOut.String())
+
+	out.Reset()
+	errOut.Reset()
+	RunREPL(strings.NewReader("5 / 0\n6 / 2\n"), out, opts)
+	assert.Equal(t, "> > 3\n> \n", out.String())
+	assert.Equal(t, "1:3: runtime error: division by zero\n", errOut.String())
+
+	out.Reset()
+	errOut.Reset()
+	RunREPL(strings.NewReader("true + 1\n1 + 1\n"), out, opts)
+	assert.Equal(t, "> > 2\n> \n", out.String())
+	assert.Equal(t, "1:6: runtime error: cannot add bool and int\n", errOut.String())
+
+	out.Reset()
+	errOut.Reset()
+	RunREPL(strings.NewReader("var = 1\n1 $ 1\n1 + 1\n"), out, opts)
+	assert.Equal(t, "> > > 2\n> \n", out.String())
+	assert.Equal(t, "1:5: expected IDENT, got = instead\n1:3: invalid token $\n", errOut.String())
+
+	out.Reset()
+	errOut.Reset()
+	opts.Sandbox = true
+	RunREPL(strings.NewReader("env(\"HOME\")\n"), out, opts)
+	assert.Equal(t, "1:1: runtime error: env is disabled in the sandbox\n", errOut.String())
+}
+
+func TestREPLMultiLine(t *testing.T) {
+	out := &bytes.Buffer{}
+	errOut := &bytes.Buffer{}
+	opts := REPLOptions{Prompt: "> ", ContinuationPrompt: ". ", ErrorOutput: errOut}
+	in := "fn add(a, b) {\n\n  return a + b\n}\nadd(1,\n2)\n\nvar s = \"a\nb\"\nlen(s)\n"
+	RunREPL(strings.NewReader(in), out, opts)
+	assert.Equal(t, "> . . . > . 3\n> > . > 3\n> \n", out.String())
+	assert.Empty(t, errOut.String())
+
+	out.Reset()
+	RunREPL(strings.NewReader("var a = [1,\n"), out, opts)
+	assert.Equal(t, "> . > \n", out.String())
+	assert.Equal(t, "1:12: unary parse function for EOF not found\n", errOut.String())
+}
+
+func TestREPLCommands(t *testing.T) {
+	out := &bytes.Buffer{}
+	errOut := &bytes.Buffer{}
+	opts := REPLOptions{Prompt: "> ", ErrorOutput: errOut}
+	in := "var s = \"x\"\nvar a = [1, 2.5]\nfn f() {}\n.vars\n.clear\n.vars\nf\n.nope\n.exit\n1 + 1\n"
+	RunREPL(strings.NewReader(in), out, opts)
+	assert.Equal(t, "> > > > a = [1, 2.5]\ns = \"x\"\n> > > > > ", out.String())
+	assert.Equal(t, "1:1: runtime error: undefined variable f\nunknown command .nope, see .help\n", errOut.String())
+
+	out.Reset()
+	RunREPL(strings.NewReader(" .help \n"), out, opts)
+	assert.Contains(t, out.String(), ".exit   leave the REPL\n")
+	assert.Equal(t, len(getREPLCommands()), strings.Count(out.String(), "\n")-1)
+}
+
+func TestIsIncomplete(t *testing.T) {
+	for _, in := range []string{"fn f() {", "f(1,", "[1, [2]", `"abc`, "if a { b(c[0]) } else {", "a /* b"} {
+		assert.True(t, isIncomplete(in), in)
+	}
+	for _, in := range []string{"", "f()", "}", "a[0] = {\"k\": 1}", "a @ b", "a /* b */"} {
+		assert.False(t, isIncomplete(in), in)
+	}
+}
+
+func TestRunInterruptible(t *testing.T) {
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		process, _ := os.FindProcess(os.Getpid())
+		process.Signal(os.Interrupt)
+	}()
+	got := runInterruptible("while true {}", uni.NewScope(nil), strings.NewReader(""), io.Discard, io.Discard, REPLOptions{})
+	assert.Equal(t, context.Canceled, got)
+}
+
+func TestCompleteNames(t *testing.T) {
+	scope := uni.NewScope(nil)
+	evaluator := uni.NewEvaluator(uni.NewParser(uni.NewLexer(`
+		var total = 0
+		var text = "abc"
+		fn triple(x) { return x * 3 }
+	`)))
+	evaluator.Eval(scope)
+
+	assert.Equal(t, []string{"text", "total", "trim", "trim_prefix", "trim_suffix", "triple", "true", "trunc", "type", "typematch"}, completeNames(scope, "t"))
+	assert.Equal(t, []string{"total"}, completeNames(uni.NewScope(scope), "to"))
+	assert.Equal(t, []string{"sorted"}, completeNames(scope, "so"))
+	assert.Equal(t, []string{"print", "println"}, completeNames(scope, "pr"))
+	assert.Equal(t, []string{}, completeNames(scope, "zzz"))
+}
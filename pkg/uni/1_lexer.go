@@ -0,0 +1,460 @@
+// Lexical analysis, lexing, or tokenization is the process of converting a sequence of
+// characters into lexical tokens. A program that performs lexical analysis may be termed
+// a lexer, tokenizer, or scanner, although scanner is also a term for the first stage of
+// a lexer. A lexer will take an input character stream and convert it into the smallest
+// meaningful characters called tokens. The stream of lexemes can be fed to a parser
+// which will convert it into a parser tree.
+
+package uni
+
+import (
+	"bufio"
+	"strings"
+	"unicode"
+)
+
+// ************
+// ** Token **
+// ************
+
+type TokenType string
+
+const (
+	ILLEGAL TokenType = "ILLEGAL"
+	EOF     TokenType = "EOF"
+
+	// Delimiters
+	COMMA     TokenType = ","
+	COLON     TokenType = ":"
+	SEMICOLON TokenType = ";"
+	LPAREN    TokenType = "("
+	RPAREN    TokenType = ")"
+	LBRACKET  TokenType = "["
+	RBRACKET  TokenType = "]"
+	LCURLY    TokenType = "{"
+	RCURLY    TokenType = "}"
+	SPREAD    TokenType = "..."
+
+	// Identifiers and literals
+	IDENT  TokenType = "IDENT"
+	INT    TokenType = "INT"
+	FLOAT  TokenType = "FLOAT"
+	STRING TokenType = "STRING"
+
+	// Keywords
+	TRUE      TokenType = "TRUE"
+	FALSE     TokenType = "FALSE"
+	VAR       TokenType = "VAR"
+	GLOBAL    TokenType = "GLOBAL"
+	IF        TokenType = "IF"
+	ELSE      TokenType = "ELSE"
+	WHILE     TokenType = "WHILE"
+	FOR       TokenType = "FOR"
+	IN        TokenType = "IN"
+	FN        TokenType = "FN"
+	RETURN    TokenType = "RETURN"
+	BREAK     TokenType = "BREAK"
+	CONTINUE  TokenType = "CONTINUE"
+	DEFER     TokenType = "DEFER"
+	LEN       TokenType = "LEN"
+	PRINT     TokenType = "PRINT"
+	PRINTLN   TokenType = "PRINTLN"
+	SPRINT    TokenType = "SPRINT"
+	SPRINTLN  TokenType = "SPRINTLN"
+	TYPEMATCH TokenType = "TYPEMATCH"
+
+	// Operators
+	ASSIGN          TokenType = "="
+	PLUS_ASSIGN     TokenType = "+="
+	MINUS_ASSIGN    TokenType = "-="
+	ASTERISK_ASSIGN TokenType = "*="
+	SLASH_ASSIGN    TokenType = "/="
+	PLUS            TokenType = "+"
+	MINUS           TokenType = "-"
+	ASTERISK        TokenType = "*"
+	SLASH           TokenType = "/"
+	PERCENT         TokenType = "%"
+	RANGE           TokenType = ".."
+	NOT             TokenType = "!"
+	LT              TokenType = "<"
+	GT              TokenType = ">"
+	LEQ             TokenType = "<="
+	GEQ             TokenType = ">="
+	EQ              TokenType = "=="
+	NEQ             TokenType = "!="
+	AMPERSAND       TokenType = "&"
+	PIPE            TokenType = "|"
+	CARET           TokenType = "^"
+	LSHIFT          TokenType = "<<"
+	RSHIFT          TokenType = ">>"
+	OR              TokenType = "OR"
+	AND             TokenType = "AND"
+)
+
+// Token is a piece of the source code. Line and Column locate its first rune, both
+// counting from 1. NewlineBefore tells that a line ends between it and the token before
+// it, which a line continued with a backslash doesn't.
+type Token struct {
+	Type          TokenType
+	Value         string
+	Line          int
+	Column        int
+	NewlineBefore bool
+}
+
+func NewToken(t TokenType, v string) Token {
+	return Token{Type: t, Value: v}
+}
+
+// ***********
+// ** Lexer **
+// ***********
+
+type Lexer struct {
+	reader *bufio.Reader
+	// line and column are the position of the next rune, and last is the position before
+	// the last rune read, for unreading it.
+	line, column int
+	last         [2]int
+	// newline is whether a line ended since the last token, and continued whether the
+	// line ending next is escaped with a backslash.
+	newline, continued bool
+}
+
+func NewLexer(in string) *Lexer {
+	return &Lexer{reader: bufio.NewReader(strings.NewReader(in)), line: 1, column: 1}
+}
+
+func (l *Lexer) Lex() chan Token {
+	tokens := make(chan Token)
+	go func() {
+		defer close(tokens)
+		for {
+			if token, ok := l.lexWhitespace(); !ok {
+				token.NewlineBefore, l.newline = l.newline, false
+				tokens <- token
+				continue
+			}
+			line, column := l.line, l.column
+			r := l.readRune()
+			var token Token
+			switch {
+			case r == 0:
+				token = NewToken(EOF, "")
+			case r == '\\' && l.isLineEnd():
+				// newlines are whitespace anyway, but a backslash at the end of a line lets
+				// a long expression be split explicitly
+				l.continued = true
+				continue
+			case r == '"':
+				token = l.lexString(r)
+			case unicode.IsDigit(r):
+				token = l.lexNumber(r)
+			case unicode.IsLetter(r) || r == '_':
+				token = l.lexIdentifier(r)
+			default:
+				token = l.lexSymbol(r)
+			}
+			token.Line, token.Column = line, column
+			token.NewlineBefore, l.newline = l.newline, false
+			tokens <- token
+			if token.Type == EOF {
+				return
+			}
+		}
+	}()
+	return tokens
+}
+
+// KeywordNames lists the keywords, in no particular order.
+func KeywordNames() []string {
+	keywords := getKeywords()
+	names := make([]string, 0, len(keywords))
+	for name := range keywords {
+		names = append(names, name)
+	}
+	return names
+}
+
+func getKeywords() map[string]TokenType {
+	return map[string]TokenType{
+		"true":      TRUE,
+		"false":     FALSE,
+		"var":       VAR,
+		"global":    GLOBAL,
+		"if":        IF,
+		"else":      ELSE,
+		"while":     WHILE,
+		"for":       FOR,
+		"in":        IN,
+		"fn":        FN,
+		"return":    RETURN,
+		"break":     BREAK,
+		"continue":  CONTINUE,
+		"defer":     DEFER,
+		"len":       LEN,
+		"print":     PRINT,
+		"println":   PRINTLN,
+		"sprint":    SPRINT,
+		"sprintln":  SPRINTLN,
+		"typematch": TYPEMATCH,
+		"or":        OR,
+		"and":       AND,
+	}
+}
+
+func (l *Lexer) lexIdentifier(r rune) Token {
+	v := string(r)
+	for {
+		r = l.readRune()
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' {
+			l.unreadRune()
+			break
+		}
+		v += string(r)
+	}
+	if t, ok := getKeywords()[v]; ok {
+		return NewToken(t, v)
+	}
+	return NewToken(IDENT, v)
+}
+
+// lexNumber reads an int, or a float with a fractional part, an exponent like e-3, or both.
+// Underscores can separate digits, as in 1_000, and are dropped from the value; anywhere
+// else, they make the number ILLEGAL.
+func (l *Lexer) lexNumber(r rune) Token {
+	t := INT
+	v := string(r)
+	for {
+		if l.isNext("..") { // the number is the start of a range
+			break
+		}
+		if prefix := l.exponentPrefix(); prefix != "" {
+			for range prefix {
+				l.readRune()
+			}
+			v += prefix + l.lexDigits()
+			t = FLOAT
+			break
+		}
+		r = l.readRune()
+		if !unicode.IsDigit(r) && r != '.' && r != '_' {
+			l.unreadRune()
+			break
+		}
+		v += string(r)
+		if r == '.' {
+			t = FLOAT
+		}
+	}
+	for i, r := range v {
+		if r == '_' && (i+1 == len(v) || !isDigit(v[i-1]) || !isDigit(v[i+1])) {
+			return NewToken(ILLEGAL, v)
+		}
+	}
+	return NewToken(t, strings.ReplaceAll(v, "_", ""))
+}
+
+func isDigit(b byte) bool {
+	return '0' <= b && b <= '9'
+}
+
+// exponentPrefix returns the e or E that starts the exponent of a number, with its sign if
+// it has one, without reading it. It's empty when the input doesn't continue with an exponent.
+func (l *Lexer) exponentPrefix() string {
+	next, _ := l.reader.Peek(3)
+	if len(next) < 2 || (next[0] != 'e' && next[0] != 'E') {
+		return ""
+	}
+	if unicode.IsDigit(rune(next[1])) {
+		return string(next[:1])
+	}
+	if len(next) == 3 && (next[1] == '+' || next[1] == '-') && unicode.IsDigit(rune(next[2])) {
+		return string(next[:2])
+	}
+	return ""
+}
+
+func (l *Lexer) lexDigits() string {
+	var digits strings.Builder
+	for {
+		r := l.readRune()
+		if !unicode.IsDigit(r) {
+			l.unreadRune()
+			return digits.String()
+		}
+		digits.WriteRune(r)
+	}
+}
+
+// lexString reads up to the closing quote, replacing the escape sequences \n, \t, \r, \",
+// and \\ with the runes they stand for. Any other backslash is kept as it is, so regular
+// expressions like "\d+" can be written without doubling it. A string that isn't closed
+// is an ILLEGAL token.
+func (l *Lexer) lexString(_ rune) Token {
+	escapes := map[rune]rune{'n': '\n', 't': '\t', 'r': '\r', '"': '"', '\\': '\\'}
+	var value strings.Builder
+	for {
+		r, err := l.read()
+		if err != nil {
+			return NewToken(ILLEGAL, `"`+value.String())
+		}
+		switch r {
+		case '"':
+			return NewToken(STRING, value.String())
+		case '\\':
+			next, err := l.read()
+			if err != nil {
+				return NewToken(ILLEGAL, `"`+value.String()+`\`)
+			}
+			if escaped, ok := escapes[next]; ok {
+				value.WriteRune(escaped)
+			} else {
+				value.WriteRune(r)
+				value.WriteRune(next)
+			}
+		default:
+			value.WriteRune(r)
+		}
+	}
+}
+
+func (l *Lexer) lexSymbol(r rune) Token {
+	symbols := map[string]TokenType{
+		",":  COMMA,
+		":":  COLON,
+		";":  SEMICOLON,
+		"(":  LPAREN,
+		")":  RPAREN,
+		"[":  LBRACKET,
+		"]":  RBRACKET,
+		"{":  LCURLY,
+		"}":  RCURLY,
+		"=":  ASSIGN,
+		"+=": PLUS_ASSIGN,
+		"-=": MINUS_ASSIGN,
+		"*=": ASTERISK_ASSIGN,
+		"/=": SLASH_ASSIGN,
+		"+":  PLUS,
+		"-":  MINUS,
+		"*":  ASTERISK,
+		"/":  SLASH,
+		"%":  PERCENT,
+		"..": RANGE,
+		"!":  NOT,
+		"<":  LT,
+		">":  GT,
+		"<=": LEQ,
+		">=": GEQ,
+		"==": EQ,
+		"!=": NEQ,
+		"&":  AMPERSAND,
+		"|":  PIPE,
+		"^":  CARET,
+		"<<": LSHIFT,
+		">>": RSHIFT,
+	}
+	singleCharSymbol := string(r)
+	next := l.readRune()
+	doubleCharSymbol := singleCharSymbol + string(next)
+	if doubleCharSymbol == ".." && l.isNext(".") {
+		l.readRune()
+		return NewToken(SPREAD, "...")
+	}
+	if t, ok := symbols[doubleCharSymbol]; ok {
+		return NewToken(t, doubleCharSymbol)
+	}
+	if next != 0 { // there is nothing to unread at the end of the input
+		l.unreadRune()
+	}
+	if t, ok := symbols[singleCharSymbol]; ok {
+		return NewToken(t, singleCharSymbol)
+	}
+	return NewToken(ILLEGAL, singleCharSymbol)
+}
+
+// lexWhitespace skips whitespace and comments, which are #, //, or /* */. For a block
+// comment still open at the end of the input, it returns an ILLEGAL token and false.
+func (l *Lexer) lexWhitespace() (Token, bool) {
+	for {
+		// peek before reading, since peeking after a read would keep it from being unread
+		line, column := l.line, l.column
+		lineComment, blockComment := l.isNext("//"), l.isNext("/*")
+		r := l.readRune()
+		switch {
+		case r == '\n':
+			l.newline = l.newline || !l.continued
+			l.continued = false
+		case r == ' ' || r == '\t' || r == '\r':
+		case r == '#' || lineComment:
+			for r != '\n' && r != 0 {
+				r = l.readRune()
+			}
+			l.newline = l.newline || r == '\n'
+		case blockComment:
+			l.readRune()
+			if !l.skipBlockComment() {
+				token := NewToken(ILLEGAL, "/*")
+				token.Line, token.Column = line, column
+				return token, false
+			}
+		default:
+			l.unreadRune()
+			return Token{}, true
+		}
+	}
+}
+
+// skipBlockComment reads up to and including the */ that closes a block comment, and
+// reports whether it found one before the end of the input.
+func (l *Lexer) skipBlockComment() bool {
+	for {
+		r, err := l.read()
+		if err != nil {
+			return false
+		}
+		if r == '*' && l.isNext("/") {
+			l.readRune()
+			return true
+		}
+	}
+}
+
+// isLineEnd reports whether the next rune ends the line, without reading it.
+func (l *Lexer) isLineEnd() bool {
+	next, err := l.reader.Peek(1)
+	return err == nil && (next[0] == '\n' || next[0] == '\r')
+}
+
+// isNext reports whether the input continues with s, without reading it.
+func (l *Lexer) isNext(s string) bool {
+	next, err := l.reader.Peek(len(s))
+	return err == nil && string(next) == s
+}
+
+// readRune reads the next rune, or returns 0 at the end of the input.
+func (l *Lexer) readRune() rune {
+	r, _ := l.read()
+	return r
+}
+
+// read reads the next rune and moves the position past it.
+func (l *Lexer) read() (rune, error) {
+	r, _, err := l.reader.ReadRune()
+	if err != nil {
+		return 0, err
+	}
+	l.last = [2]int{l.line, l.column}
+	if r == '\n' {
+		l.line, l.column = l.line+1, 1
+	} else {
+		l.column++
+	}
+	return r, nil
+}
+
+func (l *Lexer) unreadRune() {
+	if l.reader.UnreadRune() == nil {
+		l.line, l.column = l.last[0], l.last[1]
+	}
+}
@@ -0,0 +1,366 @@
+package uni
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLexer(t *testing.T) {
+	tt := []struct {
+		name string
+		in   string
+		want []Token
+	}{
+		{
+			name: "comment",
+			in:   "# Cool!",
+			want: []Token{
+				{Type: EOF, Value: ""},
+			},
+		},
+		{
+			name: "c-style comments",
+			in:   "a // line\nb /* block\nover lines * / */ c /**/ d/*x*/e / f",
+			want: []Token{
+				{Type: IDENT, Value: "a"},
+				{Type: IDENT, Value: "b"},
+				{Type: IDENT, Value: "c"},
+				{Type: IDENT, Value: "d"},
+				{Type: IDENT, Value: "e"},
+				{Type: SLASH, Value: "/"},
+				{Type: IDENT, Value: "f"},
+				{Type: EOF, Value: ""},
+			},
+		},
+		{
+			name: "unterminated block comment",
+			in:   "a /* never closed *",
+			want: []Token{
+				{Type: IDENT, Value: "a"},
+				{Type: ILLEGAL, Value: "/*"},
+				{Type: EOF, Value: ""},
+			},
+		},
+		{
+			name: "delimiters",
+			in:   ", : ( ) [ ] { }",
+			want: []Token{
+				{Type: COMMA, Value: ","},
+				{Type: COLON, Value: ":"},
+				{Type: LPAREN, Value: "("},
+				{Type: RPAREN, Value: ")"},
+				{Type: LBRACKET, Value: "["},
+				{Type: RBRACKET, Value: "]"},
+				{Type: LCURLY, Value: "{"},
+				{Type: RCURLY, Value: "}"},
+				{Type: EOF, Value: ""},
+			},
+		},
+		{
+			name: "identifiers and literals",
+			in:   `abc 0 1.5 "abc"`,
+			want: []Token{
+				{Type: IDENT, Value: "abc"},
+				{Type: INT, Value: "0"},
+				{Type: FLOAT, Value: "1.5"},
+				{Type: STRING, Value: "abc"},
+				{Type: EOF, Value: ""},
+			},
+		},
+		{
+			name: "identifiers with underscores",
+			in:   `assert_eq _tmp x_1`,
+			want: []Token{
+				{Type: IDENT, Value: "assert_eq"},
+				{Type: IDENT, Value: "_tmp"},
+				{Type: IDENT, Value: "x_1"},
+				{Type: EOF, Value: ""},
+			},
+		},
+		{
+			name: "line continuation",
+			in:   "a \\\n+ b\\\n",
+			want: []Token{
+				{Type: IDENT, Value: "a"},
+				{Type: PLUS, Value: "+"},
+				{Type: IDENT, Value: "b"},
+				{Type: EOF, Value: ""},
+			},
+		},
+		{
+			name: "keywords",
+			in:   `true false var global if else while for in fn return break continue defer len print println sprint sprintln typematch`,
+			want: []Token{
+				{Type: TRUE, Value: "true"},
+				{Type: FALSE, Value: "false"},
+				{Type: VAR, Value: "var"},
+				{Type: GLOBAL, Value: "global"},
+				{Type: IF, Value: "if"},
+				{Type: ELSE, Value: "else"},
+				{Type: WHILE, Value: "while"},
+				{Type: FOR, Value: "for"},
+				{Type: IN, Value: "in"},
+				{Type: FN, Value: "fn"},
+				{Type: RETURN, Value: "return"},
+				{Type: BREAK, Value: "break"},
+				{Type: CONTINUE, Value: "continue"},
+				{Type: DEFER, Value: "defer"},
+				{Type: LEN, Value: "len"},
+				{Type: PRINT, Value: "print"},
+				{Type: PRINTLN, Value: "println"},
+				{Type: SPRINT, Value: "sprint"},
+				{Type: SPRINTLN, Value: "sprintln"},
+				{Type: TYPEMATCH, Value: "typematch"},
+				{Type: EOF, Value: ""},
+			},
+		},
+		{
+			name: "operators",
+			in:   `= + - * / % ! < > <= >= == != or and`,
+			want: []Token{
+				{Type: ASSIGN, Value: "="},
+				{Type: PLUS, Value: "+"},
+				{Type: MINUS, Value: "-"},
+				{Type: ASTERISK, Value: "*"},
+				{Type: SLASH, Value: "/"},
+				{Type: PERCENT, Value: "%"},
+				{Type: NOT, Value: "!"},
+				{Type: LT, Value: "<"},
+				{Type: GT, Value: ">"},
+				{Type: LEQ, Value: "<="},
+				{Type: GEQ, Value: ">="},
+				{Type: EQ, Value: "=="},
+				{Type: NEQ, Value: "!="},
+				{Type: OR, Value: "or"},
+				{Type: AND, Value: "and"},
+				{Type: EOF, Value: ""},
+			},
+		},
+		{
+			name: "bitwise operators",
+			in:   `& | ^ << >> a<<b`,
+			want: []Token{
+				{Type: AMPERSAND, Value: "&"},
+				{Type: PIPE, Value: "|"},
+				{Type: CARET, Value: "^"},
+				{Type: LSHIFT, Value: "<<"},
+				{Type: RSHIFT, Value: ">>"},
+				{Type: IDENT, Value: "a"},
+				{Type: LSHIFT, Value: "<<"},
+				{Type: IDENT, Value: "b"},
+				{Type: EOF, Value: ""},
+			},
+		},
+		{
+			name: "compound assignments",
+			in:   `+= -= *= /= =-`,
+			want: []Token{
+				{Type: PLUS_ASSIGN, Value: "+="},
+				{Type: MINUS_ASSIGN, Value: "-="},
+				{Type: ASTERISK_ASSIGN, Value: "*="},
+				{Type: SLASH_ASSIGN, Value: "/="},
+				{Type: ASSIGN, Value: "="},
+				{Type: MINUS, Value: "-"},
+				{Type: EOF, Value: ""},
+			},
+		},
+		{
+			name: "string escapes",
+			in:   `"line1\nline2\t\"quoted\" \\ \d\r"`,
+			want: []Token{
+				{Type: STRING, Value: "line1\nline2\t\"quoted\" \\ \\d\r"},
+				{Type: EOF, Value: ""},
+			},
+		},
+		{
+			name: "unterminated string",
+			in:   `"abc`,
+			want: []Token{
+				{Type: ILLEGAL, Value: `"abc`},
+				{Type: EOF, Value: ""},
+			},
+		},
+		{
+			name: "string ending in an escaped quote",
+			in:   `"abc\"`,
+			want: []Token{
+				{Type: ILLEGAL, Value: `"abc"`},
+				{Type: EOF, Value: ""},
+			},
+		},
+		{
+			name: "scientific notation",
+			in:   `1e10 1.5E+2 3e-4 2e 1.0e5x`,
+			want: []Token{
+				{Type: FLOAT, Value: "1e10"},
+				{Type: FLOAT, Value: "1.5E+2"},
+				{Type: FLOAT, Value: "3e-4"},
+				{Type: INT, Value: "2"},
+				{Type: IDENT, Value: "e"},
+				{Type: FLOAT, Value: "1.0e5"},
+				{Type: IDENT, Value: "x"},
+				{Type: EOF, Value: ""},
+			},
+		},
+		{
+			name: "digit separators",
+			in:   `1_000_000 3.141_592 1_0e1_0`,
+			want: []Token{
+				{Type: INT, Value: "1000000"},
+				{Type: FLOAT, Value: "3.141592"},
+				{Type: FLOAT, Value: "10e1"},
+				{Type: IDENT, Value: "_0"},
+				{Type: EOF, Value: ""},
+			},
+		},
+		{
+			name: "misplaced digit separators",
+			in:   `1_ 1__0 1_.5 1._5 _1`,
+			want: []Token{
+				{Type: ILLEGAL, Value: "1_"},
+				{Type: ILLEGAL, Value: "1__0"},
+				{Type: ILLEGAL, Value: "1_.5"},
+				{Type: ILLEGAL, Value: "1._5"},
+				{Type: IDENT, Value: "_1"},
+				{Type: EOF, Value: ""},
+			},
+		},
+		{
+			name: "range",
+			in:   `0..10 1.5..2`,
+			want: []Token{
+				{Type: INT, Value: "0"},
+				{Type: RANGE, Value: ".."},
+				{Type: INT, Value: "10"},
+				{Type: FLOAT, Value: "1.5"},
+				{Type: RANGE, Value: ".."},
+				{Type: INT, Value: "2"},
+				{Type: EOF, Value: ""},
+			},
+		},
+		{
+			name: "spread",
+			in:   `[...a]`,
+			want: []Token{
+				{Type: LBRACKET, Value: "["},
+				{Type: SPREAD, Value: "..."},
+				{Type: IDENT, Value: "a"},
+				{Type: RBRACKET, Value: "]"},
+				{Type: EOF, Value: ""},
+			},
+		},
+		{
+			name: "semicolons",
+			in:   `i = 0; i < 3;`,
+			want: []Token{
+				{Type: IDENT, Value: "i"},
+				{Type: ASSIGN, Value: "="},
+				{Type: INT, Value: "0"},
+				{Type: SEMICOLON, Value: ";"},
+				{Type: IDENT, Value: "i"},
+				{Type: LT, Value: "<"},
+				{Type: INT, Value: "3"},
+				{Type: SEMICOLON, Value: ";"},
+				{Type: EOF, Value: ""},
+			},
+		},
+		{
+			name: "operator without spaces",
+			in:   `a>=b`,
+			want: []Token{
+				{Type: IDENT, Value: "a"},
+				{Type: GEQ, Value: ">="},
+				{Type: IDENT, Value: "b"},
+				{Type: EOF, Value: ""},
+			},
+		},
+		{
+			name: "operator followed by space",
+			in:   `a> b`,
+			want: []Token{
+				{Type: IDENT, Value: "a"},
+				{Type: GT, Value: ">"},
+				{Type: IDENT, Value: "b"},
+				{Type: EOF, Value: ""},
+			},
+		},
+		{
+			name: "operator at end of input",
+			in:   `a>`,
+			want: []Token{
+				{Type: IDENT, Value: "a"},
+				{Type: GT, Value: ">"},
+				{Type: EOF, Value: ""},
+			},
+		},
+		{
+			name: "operator preceded by space",
+			in:   `a <=b`,
+			want: []Token{
+				{Type: IDENT, Value: "a"},
+				{Type: LEQ, Value: "<="},
+				{Type: IDENT, Value: "b"},
+				{Type: EOF, Value: ""},
+			},
+		},
+		{
+			name: "operator followed by newline",
+			in:   "a <\n= b",
+			want: []Token{
+				{Type: IDENT, Value: "a"},
+				{Type: LT, Value: "<"},
+				{Type: ASSIGN, Value: "="},
+				{Type: IDENT, Value: "b"},
+				{Type: EOF, Value: ""},
+			},
+		},
+		{
+			name: "adjacent operators",
+			in:   `!!=<`,
+			want: []Token{
+				{Type: NOT, Value: "!"},
+				{Type: NEQ, Value: "!="},
+				{Type: LT, Value: "<"},
+				{Type: EOF, Value: ""},
+			},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			lexer := NewLexer(tc.in)
+			tokens := lexer.Lex()
+			for _, want := range tc.want {
+				got := <-tokens
+				got.Line, got.Column, got.NewlineBefore = 0, 0, false // positions are tested on their own
+				assert.Equal(t, want, got)
+			}
+		})
+	}
+}
+
+func TestLexerPositions(t *testing.T) {
+	in := "var s = \"é\" # note\n\n  f(s)\t+ 1..2 \\\n== ab"
+	want := []Token{
+		{Type: VAR, Value: "var", Line: 1, Column: 1},
+		{Type: IDENT, Value: "s", Line: 1, Column: 5},
+		{Type: ASSIGN, Value: "=", Line: 1, Column: 7},
+		{Type: STRING, Value: "é", Line: 1, Column: 9},
+		{Type: IDENT, Value: "f", Line: 3, Column: 3, NewlineBefore: true},
+		{Type: LPAREN, Value: "(", Line: 3, Column: 4},
+		{Type: IDENT, Value: "s", Line: 3, Column: 5},
+		{Type: RPAREN, Value: ")", Line: 3, Column: 6},
+		{Type: PLUS, Value: "+", Line: 3, Column: 8},
+		{Type: INT, Value: "1", Line: 3, Column: 10},
+		{Type: RANGE, Value: "..", Line: 3, Column: 11},
+		{Type: INT, Value: "2", Line: 3, Column: 13},
+		{Type: EQ, Value: "==", Line: 4, Column: 1},
+		{Type: IDENT, Value: "ab", Line: 4, Column: 4},
+		{Type: EOF, Value: "", Line: 4, Column: 6},
+	}
+	var got []Token
+	for token := range NewLexer(in).Lex() {
+		got = append(got, token)
+	}
+	assert.Equal(t, want, got)
+}
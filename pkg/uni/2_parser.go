@@ -0,0 +1,1161 @@
+// Parsing, or syntax analyzing is the process of converting tokens into corresponding
+// Intermediate Representation(IR) using the programming language grammar.
+// Top-down parser, and bottom-up parser are two main categories of parsers.
+// Top-down parser is classified into two types: Recursive decent parser, and LL(1).
+// Bottom-up parser is classified into two types: LR, and Operator precedence parser.
+// LR parser is classified into four types: LR(0), SLR(1), LALR(1) and CLR(1).
+//
+// Parser
+// ├── Top-down parser
+// │   ├── Recursive decent parser
+// │   └── LL(1)
+// └── Bottom-up parser
+//     ├── LR
+//     │   ├── LR(0)
+//     │   ├── SLR(1)
+//     │   ├── LALR(1)
+//     │   └── CLR(1)
+//     └── Operator precedence parser
+
+package uni
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	LOWEST  = iota + 1
+	BOOLOR  // or
+	BOOLAND // and
+	EQUALS  // == !=
+	GREATER // < > <= >=
+	BOUNDS  // ..
+	BITOR   // |
+	BITXOR  // ^
+	BITAND  // &
+	SHIFT   // << >>
+	SUM     // + -
+	PRODUCT // * /
+	PREFIX  // +x -x !x
+)
+
+// ************
+// ** Parser **
+// ************
+
+type Parser struct {
+	tokens       chan Token
+	currentToken Token
+	peekToken    Token
+	errors       []error
+	loops        int      // how many loops the current statement is in, within its function
+	labels       []string // the labels of those loops
+	functions    int      // how many function bodies the current statement is in
+}
+
+func NewParser(lexer *Lexer) *Parser {
+	return &Parser{
+		tokens: lexer.Lex(),
+		errors: make([]error, 0),
+	}
+}
+
+func (p *Parser) Errors() []error {
+	return p.errors
+}
+
+// addError records a syntax error at the current token. The parse stops at the first one,
+// and anything after it would only be a consequence of it, so only the first is kept.
+func (p *Parser) addError(err error) {
+	if len(p.errors) == 0 {
+		p.errors = append(p.errors, fmt.Errorf("%d:%d: %s", p.currentToken.Line, p.currentToken.Column, err))
+	}
+}
+
+// Parse sends every parsed statement on the returned channel, which is closed at the end
+// of the input or at the first error. A nil statement is never sent. After an error, the
+// rest of the tokens are thrown away, so the lexer isn't left waiting to send them.
+func (p *Parser) Parse() chan Statement {
+	p.next() // initialize peek token
+	p.next() // initialize current token
+	statements := make(chan Statement)
+	go func() {
+		for p.currentToken.Type != EOF && len(p.errors) == 0 {
+			if statement := p.parseStatement(); statement != nil && len(p.errors) == 0 {
+				statements <- statement
+			}
+		}
+		close(statements)
+		for range p.tokens {
+		}
+	}()
+	return statements
+}
+
+// ****************
+// ** Statements **
+// ****************
+
+type Statement interface{}
+
+func (p *Parser) parseStatement() Statement {
+	switch p.currentToken.Type {
+	case VAR, GLOBAL:
+		return p.parseVariable()
+	case IF:
+		return p.parseIf()
+	case WHILE:
+		return p.parseWhile()
+	case FOR:
+		return p.parseFor()
+	case FN:
+		if p.peekToken.Type == LPAREN { // an anonymous function, called or discarded
+			return p.parseExpression(LOWEST)
+		}
+		return p.parseFunction()
+	case RETURN:
+		return p.parseReturn()
+	case BREAK, CONTINUE:
+		return p.parseBreak()
+	case DEFER:
+		return p.parseDefer()
+	case TYPEMATCH:
+		return p.parseTypeMatch()
+	case LCURLY:
+		return p.parseBlock()
+	case IDENT:
+		if _, compound := getCompoundAssignments()[p.peekToken.Type]; compound || p.peekToken.Type == ASSIGN {
+			return p.parseVariable()
+		}
+		if p.peekToken.Type == COLON {
+			return p.parseLabeledLoop()
+		}
+		fallthrough
+	default:
+		expression := p.parseExpression(LOWEST)
+		if index, ok := expression.(Index); ok && p.currentToken.Type == ASSIGN {
+			return p.parseIndexAssign(index)
+		}
+		return expression
+	}
+}
+
+type Variable struct {
+	Name     Identifier
+	Chain    []Identifier // the names between the first one and the value in `var a = b = 0`
+	Value    Expression
+	IsNew    bool
+	IsGlobal bool
+}
+
+func (p *Parser) parseVariable() Statement {
+	v := Variable{}
+	switch p.currentToken.Type {
+	case VAR:
+		p.next() // skip var keyword
+		v.IsNew = true
+	case GLOBAL:
+		p.next() // skip global keyword
+		v.IsGlobal = true
+	}
+	v.Name = p.parseIdentifier().(Identifier)
+	if operator, ok := getCompoundAssignments()[p.currentToken.Type]; ok && !v.IsNew && !v.IsGlobal {
+		// x += 1 is x = x + 1, except that x has to exist already
+		token := p.currentToken
+		p.next() // skip compound assignment symbol
+		v.Value = BinaryOperation{
+			Token: Token{Type: operator, Value: string(operator), Line: token.Line, Column: token.Column},
+			Left:  v.Name,
+			Right: p.parseExpression(LOWEST),
+		}
+		return v
+	}
+	if !p.expectCurrent(ASSIGN) {
+		return nil
+	}
+	p.next() // skip = symbol
+	for p.currentToken.Type == IDENT && p.peekToken.Type == ASSIGN {
+		v.Chain = append(v.Chain, p.parseIdentifier().(Identifier))
+		p.next() // skip = symbol
+	}
+	v.Value = p.parseExpression(LOWEST)
+	return v
+}
+
+// getCompoundAssignments maps each compound assignment to the operation it applies.
+func getCompoundAssignments() map[TokenType]TokenType {
+	return map[TokenType]TokenType{
+		PLUS_ASSIGN:     PLUS,
+		MINUS_ASSIGN:    MINUS,
+		ASTERISK_ASSIGN: ASTERISK,
+		SLASH_ASSIGN:    SLASH,
+	}
+}
+
+// IndexAssign sets an item of an array or a map, changing it in place.
+type IndexAssign struct {
+	Target Index
+	Value  Expression
+}
+
+func (p *Parser) parseIndexAssign(target Index) Statement {
+	p.next() // skip = symbol
+	return IndexAssign{Target: target, Value: p.parseExpression(LOWEST)}
+}
+
+type If struct {
+	Condition   Expression
+	Consequence Block
+	Alternative *Block
+}
+
+func (p *Parser) parseIf() Statement {
+	p.next() // skip if keyword
+	i := If{}
+	i.Condition = p.parseCondition()
+	if i.Condition == nil || !p.expectCurrent(LCURLY) {
+		return nil
+	}
+	i.Consequence = p.parseBlock().(Block)
+	if p.currentToken.Type == ELSE {
+		p.next() // skip else keyword
+		if p.currentToken.Type == IF {
+			// else if is an else block holding just the next if
+			alternative := Block{Statements: []Statement{p.parseIf()}}
+			i.Alternative = &alternative
+			return i
+		}
+		if !p.expectCurrent(LCURLY) {
+			return nil
+		}
+		alternative := p.parseBlock().(Block)
+		i.Alternative = &alternative
+	}
+	return i
+}
+
+type While struct {
+	Label       Identifier
+	Condition   Expression
+	Consequence Block
+}
+
+func (p *Parser) parseWhile() Statement {
+	p.next() // skip while keyword
+	w := While{Condition: p.parseCondition()}
+	if w.Condition == nil || !p.expectCurrent(LCURLY) {
+		return nil
+	}
+	w.Consequence = p.parseLoopBody()
+	return w
+}
+
+type For struct {
+	Label       Identifier
+	Key         Identifier
+	Value       Identifier
+	Condition   Expression
+	Consequence Block
+}
+
+func (p *Parser) parseFor() Statement {
+	p.next() // skip for keyword
+	if p.currentToken.Type == VAR || p.currentToken.Type == SEMICOLON || p.peekToken.Type == ASSIGN {
+		return p.parseForClassic()
+	}
+	f := For{Key: p.parseIdentifier().(Identifier)}
+	if p.currentToken.Type == COMMA {
+		p.next() // skip , symbol
+		f.Value = p.parseIdentifier().(Identifier)
+	}
+	if !p.expectCurrent(IN) {
+		return nil
+	}
+	p.next() // read in keyword
+	f.Condition = p.parseExpression(LOWEST)
+	if !p.expectCurrent(LCURLY) {
+		return nil
+	}
+	f.Consequence = p.parseLoopBody()
+	return f
+}
+
+// ForClassic is the `for init; condition; post {}` loop. Each of the three clauses can be
+// left out, and a missing condition is always true.
+type ForClassic struct {
+	Label       Identifier
+	Init        Statement
+	Condition   Expression
+	Post        Statement
+	Consequence Block
+}
+
+func (p *Parser) parseForClassic() Statement {
+	f := ForClassic{}
+	if p.currentToken.Type != SEMICOLON {
+		init, ok := p.parseVariable().(Variable)
+		if !ok {
+			return nil
+		}
+		init.IsNew = true // the loop variable belongs to the loop, even without var
+		f.Init = init
+	}
+	if !p.expectCurrent(SEMICOLON) {
+		return nil
+	}
+	p.next() // skip ; symbol
+	if p.currentToken.Type != SEMICOLON {
+		f.Condition = p.parseExpression(LOWEST)
+	}
+	if !p.expectCurrent(SEMICOLON) {
+		return nil
+	}
+	p.next() // skip ; symbol
+	if p.currentToken.Type != LCURLY {
+		f.Post = p.parseStatement()
+	}
+	if !p.expectCurrent(LCURLY) {
+		return nil
+	}
+	f.Consequence = p.parseLoopBody()
+	return f
+}
+
+// parseLabeledLoop parses `label: while ...` or `label: for ...`, whose label break and
+// continue can name to reach past the loops nested inside it.
+func (p *Parser) parseLabeledLoop() Statement {
+	label := p.parseIdentifier().(Identifier)
+	p.next() // skip : symbol
+	if !p.expectCurrent(WHILE, FOR) {
+		return nil
+	}
+	p.labels = append(p.labels, label.Token.Value)
+	defer func() { p.labels = p.labels[:len(p.labels)-1] }()
+	switch loop := p.parseStatement().(type) {
+	case While:
+		loop.Label = label
+		return loop
+	case For:
+		loop.Label = label
+		return loop
+	case ForClassic:
+		loop.Label = label
+		return loop
+	default:
+		return nil
+	}
+}
+
+func (p *Parser) parseLoopBody() Block {
+	p.loops++
+	defer func() { p.loops-- }()
+	return p.parseBlock().(Block)
+}
+
+// Function is a function declaration, or, without a name, an anonymous function expression.
+type Function struct {
+	Name       Identifier
+	Parameters []Identifier
+	Body       Block
+	scope      *Scope // where an anonymous function was evaluated, which its body sees
+}
+
+func (p *Parser) parseFunction() Statement {
+	p.next() // skip fn keyword
+	f := Function{}
+	if p.currentToken.Type != LPAREN {
+		f.Name = p.parseIdentifier().(Identifier)
+	}
+	if !p.expectCurrent(LPAREN) {
+		return nil
+	}
+	p.next() // skip ( symbol
+	for p.currentToken.Type != RPAREN && len(p.errors) == 0 {
+		f.Parameters = append(f.Parameters, p.parseIdentifier().(Identifier))
+		if p.currentToken.Type == COMMA {
+			p.next() // skip , symbol
+		}
+	}
+	p.next() // skip ) symbol
+	if !p.expectCurrent(LCURLY) {
+		return nil
+	}
+	loops, labels := p.loops, p.labels
+	p.loops, p.labels = 0, nil // a loop around the definition isn't around the body when it runs
+	p.functions++
+	f.Body = p.parseBlock().(Block)
+	p.functions--
+	p.loops, p.labels = loops, labels
+	return f
+}
+
+type Return struct {
+	Value Expression
+}
+
+// parseReturn parses a return statement, whose value starts on the same line. Without a
+// value, as when it's followed by } or by a statement on the next line, it returns nil.
+func (p *Parser) parseReturn() Statement {
+	p.next() // skip return keyword
+	if !startsExpression(p.currentToken.Type) || p.currentToken.NewlineBefore {
+		return Return{}
+	}
+	return Return{Value: p.parseExpression(LOWEST)}
+}
+
+// startsExpression reports whether a token of the type can be the first of an expression.
+func startsExpression(t TokenType) bool {
+	switch t {
+	case TRUE, FALSE, INT, FLOAT, STRING, PLUS, MINUS, NOT, IDENT, LPAREN, LBRACKET, LCURLY,
+		FN, LEN, PRINT, PRINTLN, SPRINT, SPRINTLN, ILLEGAL:
+		return true
+	default:
+		return false
+	}
+}
+
+// Break and Continue stop the innermost loop, or skip to its next iteration. With a label,
+// they do that to the enclosing loop with that label instead.
+type Break struct {
+	Label Identifier
+}
+
+type Continue struct {
+	Label Identifier
+}
+
+func (p *Parser) parseBreak() Statement {
+	keyword := p.currentToken
+	if p.loops == 0 {
+		p.addError(fmt.Errorf("%s outside a loop", keyword.Value))
+		return nil
+	}
+	p.next() // skip break or continue keyword
+	var label Identifier
+	// without the end of the line to go by, a name is only a label if a loop has it
+	if p.currentToken.Type == IDENT && p.isLabel(p.currentToken.Value) {
+		label = p.parseIdentifier().(Identifier)
+	}
+	if keyword.Type == BREAK {
+		return Break{Label: label}
+	}
+	return Continue{Label: label}
+}
+
+func (p *Parser) isLabel(name string) bool {
+	for _, label := range p.labels {
+		if label == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Defer schedules an expression, usually a call, to be evaluated when the function it's in
+// returns. The deferred expressions run in the reverse of the order they were deferred in.
+type Defer struct {
+	Value Expression
+}
+
+func (p *Parser) parseDefer() Statement {
+	if p.functions == 0 {
+		p.addError(fmt.Errorf("defer outside a function"))
+		return nil
+	}
+	p.next() // skip defer keyword
+	return Defer{Value: p.parseExpression(LOWEST)}
+}
+
+type Block struct {
+	Statements []Statement
+}
+
+// TypeMatch runs the block of the first case that names the type of the subject, or the
+// else block when there is none.
+type TypeMatch struct {
+	Subject Expression
+	Cases   []TypeCase
+	Else    *Block
+}
+
+type TypeCase struct {
+	Types []Identifier
+	Body  Block
+}
+
+func (p *Parser) parseTypeMatch() Statement {
+	p.next() // skip typematch keyword
+	t := TypeMatch{Subject: p.parseExpression(LOWEST)}
+	if !p.expectCurrent(LCURLY) {
+		return nil
+	}
+	p.next() // skip { symbol
+	for p.currentToken.Type != RCURLY && len(p.errors) == 0 {
+		if p.currentToken.Type == ELSE {
+			p.next() // skip else keyword
+			if !p.expectCurrent(COLON) {
+				return nil
+			}
+			p.next() // skip : symbol
+			if !p.expectCurrent(LCURLY) {
+				return nil
+			}
+			body := p.parseBlock().(Block)
+			t.Else = &body
+			continue
+		}
+		c := TypeCase{}
+		for {
+			if !p.expectCurrent(IDENT) {
+				return nil
+			}
+			if !isTypeName(p.currentToken.Value) {
+				p.addError(fmt.Errorf("unknown type %s in typematch", p.currentToken.Value))
+				return nil
+			}
+			c.Types = append(c.Types, p.parseIdentifier().(Identifier))
+			if p.currentToken.Type != COMMA {
+				break
+			}
+			p.next() // skip , symbol
+		}
+		if !p.expectCurrent(COLON) {
+			return nil
+		}
+		p.next() // skip : symbol
+		if !p.expectCurrent(LCURLY) {
+			return nil
+		}
+		c.Body = p.parseBlock().(Block)
+		t.Cases = append(t.Cases, c)
+	}
+	p.next() // skip } symbol
+	return t
+}
+
+func isTypeName(name string) bool {
+	switch name {
+	case "nil", "bool", "int", "float", "string", "array", "map", "function":
+		return true
+	default:
+		return false
+	}
+}
+
+func (p *Parser) parseBlock() Statement {
+	p.next() // skip { symbol
+	b := Block{}
+	for p.currentToken.Type != RCURLY && len(p.errors) == 0 {
+		b.Statements = append(b.Statements, p.parseStatement())
+	}
+	p.next() // skip } symbol
+	return b
+}
+
+// *****************
+// ** Expressions **
+// *****************
+
+type Expression interface{}
+
+func (p *Parser) parseExpression(precedence int) Expression {
+	var left Expression
+	switch p.currentToken.Type {
+	case TRUE, FALSE:
+		left = p.parseBoolean()
+	case INT:
+		left = p.parseInteger()
+	case FLOAT:
+		left = p.parseFloat()
+	case STRING:
+		left = p.parseString()
+	case PLUS, MINUS, NOT:
+		left = p.parseUnaryOperation()
+	case IDENT:
+		left = p.parseIdentifier()
+	case LPAREN:
+		p.next() // skip ( symbol
+		left = p.parseExpression(LOWEST)
+		p.next() // skip ) symbol
+	case LBRACKET:
+		left = p.parseArray()
+	case LCURLY:
+		left = p.parseMap()
+	case FN:
+		left = p.parseFunction()
+	case LEN:
+		left = p.parseLen()
+	case PRINT, PRINTLN, SPRINT, SPRINTLN:
+		left = p.parsePrint()
+	case ILLEGAL:
+		if p.currentToken.Value == "/*" {
+			p.addError(fmt.Errorf("unterminated block comment"))
+			return nil
+		}
+		p.addError(fmt.Errorf("invalid token %s", p.currentToken.Value))
+		return nil
+	default:
+		p.addError(fmt.Errorf("unary parse function for %s not found", p.currentToken.Type))
+		return nil
+	}
+	if left == nil {
+		return nil
+	}
+	left = p.parseSuffixes(left)
+	for precedence < getPrecedence(p.currentToken.Type) {
+		switch p.currentToken.Type {
+		case OR, AND, PLUS, MINUS, ASTERISK, SLASH, PERCENT, EQ, NEQ, LT, GT, LEQ, GEQ,
+			AMPERSAND, PIPE, CARET, LSHIFT, RSHIFT:
+			left = p.parseBinaryOperation(left)
+		case RANGE:
+			left = p.parseRange(left)
+		default:
+			p.addError(fmt.Errorf("binary parse function for %s not found", p.currentToken.Type))
+			return nil
+		}
+	}
+	return left
+}
+
+// parseCondition parses the condition of an if or a while, catching the common mistake of
+// writing = where == was meant.
+func (p *Parser) parseCondition() Expression {
+	condition := p.parseExpression(LOWEST)
+	if p.currentToken.Type == ASSIGN {
+		p.addError(fmt.Errorf("unexpected = in condition, did you mean ==?"))
+		return nil
+	}
+	return condition
+}
+
+type Boolean struct {
+	Value bool
+}
+
+func (p *Parser) parseBoolean() Expression {
+	if !p.expectCurrent(TRUE, FALSE) {
+		return nil
+	}
+	b := Boolean{}
+	b.Value, _ = strconv.ParseBool(p.currentToken.Value)
+	p.next() // skip boolean literal
+	return b
+}
+
+type Integer struct {
+	Value int64
+}
+
+func (p *Parser) parseInteger() Expression {
+	if !p.expectCurrent(INT) {
+		return nil
+	}
+	i := Integer{}
+	i.Value, _ = strconv.ParseInt(p.currentToken.Value, 10, 64)
+	p.next() // skip integer literal
+	return i
+}
+
+type Float struct {
+	Value float64
+}
+
+func (p *Parser) parseFloat() Expression {
+	if !p.expectCurrent(FLOAT) {
+		return nil
+	}
+	f := Float{}
+	f.Value, _ = strconv.ParseFloat(p.currentToken.Value, 64)
+	p.next() // skip float literal
+	return f
+}
+
+type String struct {
+	Value string
+}
+
+func (p *Parser) parseString() Expression {
+	if !p.expectCurrent(STRING) {
+		return nil
+	}
+	s := String{Value: p.currentToken.Value}
+	p.next() // skip string literal
+	return s
+}
+
+type Array struct {
+	Items []Expression
+}
+
+func (p *Parser) parseArray() Expression {
+	p.next() // skip [ symbol
+	a := Array{Items: make([]Expression, 0)}
+	for p.currentToken.Type != RBRACKET && len(p.errors) == 0 {
+		a.Items = append(a.Items, p.parseItem())
+		if p.currentToken.Type == COMMA {
+			p.next() // skip , symbol
+		}
+	}
+	p.next() // skip ] symbol
+	return a
+}
+
+// Spread splices the items of an array into the array literal or the arguments it's in.
+type Spread struct {
+	Token Token // the ... symbol
+	Value Expression
+}
+
+// parseItem parses an item of an array literal or an argument of a call, either of which
+// can be spread.
+func (p *Parser) parseItem() Expression {
+	if p.currentToken.Type != SPREAD {
+		return p.parseExpression(LOWEST)
+	}
+	spread := Spread{Token: p.currentToken}
+	p.next() // skip ... symbol
+	spread.Value = p.parseExpression(LOWEST)
+	return spread
+}
+
+// Map is a map literal. Its items are kept in the order they are written, since a key can
+// be any expression, and not every expression can be a Go map key.
+type Map struct {
+	Items []MapItem
+}
+
+type MapItem struct {
+	Key   Expression
+	Value Expression
+}
+
+func (p *Parser) parseMap() Expression {
+	p.next() // skip { symbol
+	m := Map{Items: make([]MapItem, 0)}
+	for p.currentToken.Type != RCURLY && len(p.errors) == 0 {
+		key := p.parseExpression(LOWEST)
+		if !p.expectCurrent(COLON) {
+			return nil
+		}
+		p.next() // skip : symbol
+		m.Items = append(m.Items, MapItem{Key: key, Value: p.parseExpression(LOWEST)})
+		if p.currentToken.Type == COMMA {
+			p.next() // skip , symbol
+		}
+	}
+	p.next() // skip } symbol
+	return m
+}
+
+// parseSuffixes applies any number of index and call suffixes to left, so that a[0][1],
+// f()[0], and a[0]() chain from left to right. A suffix starts on the line left ends on,
+// since a [ or ( starting a line starts an array or an expression of its own.
+func (p *Parser) parseSuffixes(left Expression) Expression {
+	for {
+		if p.currentToken.NewlineBefore {
+			return left
+		}
+		switch p.currentToken.Type {
+		case LBRACKET:
+			left = p.parseIndex(left)
+		case LPAREN:
+			left = p.parseCall(left)
+		default:
+			return left
+		}
+	}
+}
+
+type Index struct {
+	Index   Expression
+	Subject Expression
+}
+
+// parseIndex parses a[i], or the slice a[start:end], either bound of which can be left out.
+func (p *Parser) parseIndex(left Expression) Expression {
+	p.next() // skip [ symbol
+	var index Expression
+	if p.currentToken.Type != COLON {
+		index = p.parseExpression(LOWEST)
+	}
+	if p.currentToken.Type == COLON {
+		p.next() // skip : symbol
+		s := Slice{Subject: left, Start: index}
+		if p.currentToken.Type != RBRACKET {
+			s.End = p.parseExpression(LOWEST)
+		}
+		if !p.expectCurrent(RBRACKET) {
+			return nil
+		}
+		p.next() // skip ] symbol
+		return s
+	}
+	if !p.expectCurrent(RBRACKET) {
+		return nil
+	}
+	p.next() // skip ] symbol
+	return Index{Index: index, Subject: left}
+}
+
+// Slice is the part of a string or an array from Start up to, but not including, End. A
+// missing Start is the beginning, and a missing End is the end.
+type Slice struct {
+	Subject Expression
+	Start   Expression
+	End     Expression
+}
+
+type Call struct {
+	Function  Expression
+	Arguments []Expression
+}
+
+func (p *Parser) parseCall(left Expression) Expression {
+	p.next() // skip ( symbol
+	c := Call{Function: left, Arguments: make([]Expression, 0)}
+	for p.currentToken.Type != RPAREN && len(p.errors) == 0 {
+		c.Arguments = append(c.Arguments, p.parseItem())
+		if p.currentToken.Type == COMMA {
+			p.next() // skip , symbol
+		}
+	}
+	p.next() // skip ) symbol
+	return c
+}
+
+type Identifier struct {
+	Token          Token
+	IsFunctionCall bool
+}
+
+// parseIdentifier parses a name. Without one, it records the error and returns an empty
+// Identifier, so callers can keep going until the error stops the parse.
+func (p *Parser) parseIdentifier() Expression {
+	if !p.expectCurrent(IDENT) {
+		return Identifier{}
+	}
+	i := Identifier{Token: p.currentToken, IsFunctionCall: p.peekToken.Type == LPAREN}
+	p.next() // skip identifier
+	return i
+}
+
+type UnaryOperation struct {
+	Token      Token
+	Expression Expression
+}
+
+func (p *Parser) parseUnaryOperation() Expression {
+	if !p.expectCurrent(PLUS, MINUS, NOT) {
+		return nil
+	}
+	uo := UnaryOperation{Token: p.currentToken}
+	p.next() // skip +, -, or !
+	uo.Expression = p.parseExpression(PREFIX)
+	return uo
+}
+
+type BinaryOperation struct {
+	Token Token
+	Left  Expression
+	Right Expression
+}
+
+func (p *Parser) parseBinaryOperation(left Expression) Expression {
+	bo := BinaryOperation{Token: p.currentToken, Left: left}
+	precedence := getPrecedence(p.currentToken.Type)
+	p.next() // skip operator(+, -, ...)
+	bo.Right = p.parseExpression(precedence)
+	return bo
+}
+
+// Range is the ints from Start up to, but not including, End.
+type Range struct {
+	Start Expression
+	End   Expression
+}
+
+func (p *Parser) parseRange(left Expression) Expression {
+	p.next() // skip .. symbol
+	return Range{Start: left, End: p.parseExpression(BOUNDS)}
+}
+
+type Len struct {
+	Subject Expression
+}
+
+func (p *Parser) parseLen() Expression {
+	p.next() // skip len keyword
+	p.next() // skip ( symbol
+	l := Len{Subject: p.parseExpression(LOWEST)}
+	p.next() // skip ) symbol
+	return l
+}
+
+type Print struct {
+	Args      []Expression
+	Separator Expression
+	End       Expression
+	IsNewLine bool
+	IsString  bool
+}
+
+func (p *Parser) parsePrint() Expression {
+	print := Print{
+		IsNewLine: p.currentToken.Type == PRINTLN || p.currentToken.Type == SPRINTLN,
+		IsString:  p.currentToken.Type == SPRINT || p.currentToken.Type == SPRINTLN,
+	}
+	p.next() // skip print, println, sprint, or sprintln keyword
+	if !p.expectCurrent(LPAREN) {
+		return nil
+	}
+	p.next() // skip ( symbol
+	for p.currentToken.Type != RPAREN && len(p.errors) == 0 {
+		switch {
+		case p.currentToken.Value == "sep" && p.peekToken.Type == ASSIGN:
+			p.next() // skip sep
+			p.next() // skip = symbol
+			print.Separator = p.parseExpression(LOWEST)
+		case p.currentToken.Value == "end" && p.peekToken.Type == ASSIGN:
+			p.next() // skip end
+			p.next() // skip = symbol
+			print.End = p.parseExpression(LOWEST)
+		default:
+			print.Args = append(print.Args, p.parseItem())
+		}
+		if p.currentToken.Type == COMMA {
+			p.next() // skip , symbol
+		}
+	}
+	p.next() // skip ) symbol
+	return print
+}
+
+func (p *Parser) expectCurrent(tokenTypes ...TokenType) bool {
+	for _, tokenType := range tokenTypes {
+		if p.currentToken.Type == tokenType {
+			return true
+		}
+	}
+	if len(tokenTypes) == 1 {
+		p.addError(fmt.Errorf("expected %s, got %s instead", tokenTypes[0], p.currentToken.Type))
+	} else {
+		p.addError(fmt.Errorf("expected one of %s, got %s instead", tokenTypes, p.currentToken.Type))
+	}
+	return false
+}
+
+func getPrecedence(in TokenType) int {
+	precedences := map[TokenType]int{
+		EQ:        EQUALS,
+		NEQ:       EQUALS,
+		OR:        BOOLOR,
+		AND:       BOOLAND,
+		LT:        GREATER,
+		GT:        GREATER,
+		LEQ:       GREATER,
+		GEQ:       GREATER,
+		RANGE:     BOUNDS,
+		PIPE:      BITOR,
+		CARET:     BITXOR,
+		AMPERSAND: BITAND,
+		LSHIFT:    SHIFT,
+		RSHIFT:    SHIFT,
+		PLUS:      SUM,
+		MINUS:     SUM,
+		ASTERISK:  PRODUCT,
+		SLASH:     PRODUCT,
+		PERCENT:   PRODUCT,
+	}
+	if precedence, ok := precedences[in]; ok {
+		return precedence
+	}
+	return LOWEST
+}
+
+func (p *Parser) next() {
+	p.currentToken = p.peekToken
+	p.peekToken = <-p.tokens
+}
+
+// **********
+// ** Dump **
+// **********
+
+// Dump renders a statement or an expression back into source form, wrapping every unary
+// and binary operation in parentheses so the structure the parser built is visible.
+func Dump(node any) string {
+	return dump(node, 0)
+}
+
+func dump(node any, depth int) string {
+	switch n := node.(type) {
+	case nil:
+		return "nil"
+	case Variable:
+		out := dump(n.Name, depth) + " = "
+		for _, name := range n.Chain {
+			out += dump(name, depth) + " = "
+		}
+		out += dump(n.Value, depth)
+		if n.IsNew {
+			return "var " + out
+		}
+		if n.IsGlobal {
+			return "global " + out
+		}
+		return out
+	case IndexAssign:
+		return dump(n.Target, depth) + " = " + dump(n.Value, depth)
+	case If:
+		out := fmt.Sprintf("if %s %s", dump(n.Condition, depth), dump(n.Consequence, depth))
+		if n.Alternative != nil {
+			if statements := n.Alternative.Statements; len(statements) == 1 {
+				if next, ok := statements[0].(If); ok {
+					return out + " else " + dump(next, depth)
+				}
+			}
+			out += " else " + dump(*n.Alternative, depth)
+		}
+		return out
+	case While:
+		return dumpLabel(n.Label) + fmt.Sprintf("while %s %s", dump(n.Condition, depth), dump(n.Consequence, depth))
+	case For:
+		variables := dump(n.Key, depth)
+		if n.Value.Token.Value != "" {
+			variables += ", " + dump(n.Value, depth)
+		}
+		return dumpLabel(n.Label) + fmt.Sprintf("for %s in %s %s", variables, dump(n.Condition, depth), dump(n.Consequence, depth))
+	case Function:
+		name := dump(n.Name, depth)
+		if name != "" {
+			name = " " + name
+		}
+		return fmt.Sprintf("fn%s(%s) %s", name, dumpList(n.Parameters, depth), dump(n.Body, depth))
+	case ForClassic:
+		out := dumpLabel(n.Label) + "for "
+		if n.Init != nil {
+			out += dump(n.Init, depth)
+		}
+		out += "; "
+		if n.Condition != nil {
+			out += dump(n.Condition, depth)
+		}
+		out += "; "
+		if n.Post != nil {
+			out += dump(n.Post, depth) + " "
+		}
+		return out + dump(n.Consequence, depth)
+	case Return:
+		if n.Value == nil {
+			return "return"
+		}
+		return "return " + dump(n.Value, depth)
+	case Defer:
+		return "defer " + dump(n.Value, depth)
+	case Break:
+		return strings.TrimSpace("break " + n.Label.Token.Value)
+	case Continue:
+		return strings.TrimSpace("continue " + n.Label.Token.Value)
+	case TypeMatch:
+		indent := strings.Repeat("    ", depth+1)
+		out := "typematch " + dump(n.Subject, depth) + " {\n"
+		for _, c := range n.Cases {
+			out += indent + dumpList(c.Types, depth+1) + ": " + dump(c.Body, depth+1) + "\n"
+		}
+		if n.Else != nil {
+			out += indent + "else: " + dump(*n.Else, depth+1) + "\n"
+		}
+		return out + strings.Repeat("    ", depth) + "}"
+	case Block:
+		if len(n.Statements) == 0 {
+			return "{}"
+		}
+		indent := strings.Repeat("    ", depth+1)
+		out := "{\n"
+		for _, statement := range n.Statements {
+			out += indent + dump(statement, depth+1) + "\n"
+		}
+		return out + strings.Repeat("    ", depth) + "}"
+	case Boolean:
+		return strconv.FormatBool(n.Value)
+	case Integer:
+		return strconv.FormatInt(n.Value, 10)
+	case Float:
+		out := strconv.FormatFloat(n.Value, 'g', -1, 64)
+		if !strings.ContainsAny(out, ".eEnN") {
+			out += ".0"
+		}
+		return out
+	case String:
+		return strconv.Quote(n.Value)
+	case Array:
+		return "[" + dumpList(n.Items, depth) + "]"
+	case Map:
+		items := make([]string, 0, len(n.Items))
+		for _, item := range n.Items {
+			items = append(items, dump(item.Key, depth)+": "+dump(item.Value, depth))
+		}
+		return "{" + strings.Join(items, ", ") + "}"
+	case Spread:
+		return "..." + dump(n.Value, depth)
+	case Index:
+		return fmt.Sprintf("%s[%s]", dump(n.Subject, depth), dump(n.Index, depth))
+	case Slice:
+		var start, end string
+		if n.Start != nil {
+			start = dump(n.Start, depth)
+		}
+		if n.End != nil {
+			end = dump(n.End, depth)
+		}
+		return fmt.Sprintf("%s[%s:%s]", dump(n.Subject, depth), start, end)
+	case Call:
+		return fmt.Sprintf("%s(%s)", dump(n.Function, depth), dumpList(n.Arguments, depth))
+	case Identifier:
+		return n.Token.Value
+	case UnaryOperation:
+		return fmt.Sprintf("(%s%s)", n.Token.Value, dump(n.Expression, depth))
+	case BinaryOperation:
+		return fmt.Sprintf("(%s %s %s)", dump(n.Left, depth), n.Token.Value, dump(n.Right, depth))
+	case Range:
+		return fmt.Sprintf("(%s..%s)", dump(n.Start, depth), dump(n.End, depth))
+	case Len:
+		return fmt.Sprintf("len(%s)", dump(n.Subject, depth))
+	case Print:
+		args := dumpList(n.Args, depth)
+		if n.Separator != nil {
+			args += ", sep=" + dump(n.Separator, depth)
+		}
+		if n.End != nil {
+			args += ", end=" + dump(n.End, depth)
+		}
+		args = strings.TrimPrefix(args, ", ")
+		name := "print"
+		if n.IsString {
+			name = "sprint"
+		}
+		if n.IsNewLine {
+			name += "ln"
+		}
+		return fmt.Sprintf("%s(%s)", name, args)
+	default:
+		return fmt.Sprintf("%#v", n)
+	}
+}
+
+func dumpLabel(label Identifier) string {
+	if label.Token.Value == "" {
+		return ""
+	}
+	return label.Token.Value + ": "
+}
+
+func dumpList[T any](nodes []T, depth int) string {
+	items := make([]string, len(nodes))
+	for i, node := range nodes {
+		items[i] = dump(node, depth)
+	}
+	return strings.Join(items, ", ")
+}
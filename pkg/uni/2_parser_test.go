@@ -0,0 +1,809 @@
+package uni
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParser(t *testing.T) {
+	tt := []struct {
+		name string
+		in   string
+		want []Statement
+	}{
+		{
+			name: "expression 1",
+			in:   "(1 + 2) * 3",
+			want: []Statement{
+				BinaryOperation{
+					Token: NewToken(ASTERISK, "*"),
+					Left: BinaryOperation{
+						Token: NewToken(PLUS, "+"),
+						Left:  Integer{Value: 1},
+						Right: Integer{Value: 2},
+					},
+					Right: Integer{Value: 3},
+				},
+			},
+		},
+		{
+			name: "expression 2",
+			in:   "sum(1, 2)",
+			want: []Statement{
+				Call{
+					Function: Identifier{
+						Token:          NewToken(IDENT, "sum"),
+						IsFunctionCall: true,
+					},
+					Arguments: []Expression{
+						Integer{Value: 1},
+						Integer{Value: 2},
+					},
+				},
+			},
+		},
+		{
+			name: "chained index",
+			in:   "a[0][1]",
+			want: []Statement{
+				Index{
+					Index: Integer{Value: 1},
+					Subject: Index{
+						Index:   Integer{Value: 0},
+						Subject: Identifier{Token: NewToken(IDENT, "a")},
+					},
+				},
+			},
+		},
+		{
+			name: "index of call",
+			in:   "f()[0]",
+			want: []Statement{
+				Index{
+					Index: Integer{Value: 0},
+					Subject: Call{
+						Function:  Identifier{Token: NewToken(IDENT, "f"), IsFunctionCall: true},
+						Arguments: []Expression{},
+					},
+				},
+			},
+		},
+		{
+			name: "call of index",
+			in:   "a[0](1)",
+			want: []Statement{
+				Call{
+					Function: Index{
+						Index:   Integer{Value: 0},
+						Subject: Identifier{Token: NewToken(IDENT, "a")},
+					},
+					Arguments: []Expression{Integer{Value: 1}},
+				},
+			},
+		},
+		{
+			name: "slice",
+			in:   "a[1:n]\na[:]",
+			want: []Statement{
+				Slice{
+					Subject: Identifier{Token: NewToken(IDENT, "a")},
+					Start:   Integer{Value: 1},
+					End:     Identifier{Token: NewToken(IDENT, "n")},
+				},
+				Slice{
+					Subject: Identifier{Token: NewToken(IDENT, "a")},
+				},
+			},
+		},
+		{
+			name: "compound assignment",
+			in:   "a -= 1",
+			want: []Statement{
+				Variable{
+					Name: Identifier{Token: NewToken(IDENT, "a")},
+					Value: BinaryOperation{
+						Token: NewToken(MINUS, "-"),
+						Left:  Identifier{Token: NewToken(IDENT, "a")},
+						Right: Integer{Value: 1},
+					},
+				},
+			},
+		},
+		{
+			name: "negative index",
+			in:   "a[-1]",
+			want: []Statement{
+				Index{
+					Index: UnaryOperation{
+						Token:      NewToken(MINUS, "-"),
+						Expression: Integer{Value: 1},
+					},
+					Subject: Identifier{
+						Token:          NewToken(IDENT, "a"),
+						IsFunctionCall: false,
+					},
+				},
+			},
+		},
+		{
+			name: "negative map key",
+			in:   `var m = {-1: "x", -2.5: "y"}`,
+			want: []Statement{
+				Variable{
+					Name: Identifier{
+						Token:          NewToken(IDENT, "m"),
+						IsFunctionCall: false,
+					},
+					Value: Map{
+						Items: []MapItem{
+							{Key: UnaryOperation{Token: NewToken(MINUS, "-"), Expression: Integer{Value: 1}}, Value: String{Value: "x"}},
+							{Key: UnaryOperation{Token: NewToken(MINUS, "-"), Expression: Float{Value: 2.5}}, Value: String{Value: "y"}},
+						},
+					},
+					IsNew: true,
+				},
+			},
+		},
+		{
+			name: "variable 1",
+			in:   "var a = 0",
+			want: []Statement{
+				Variable{
+					Name: Identifier{
+						Token:          NewToken(IDENT, "a"),
+						IsFunctionCall: false,
+					},
+					Value: Integer{Value: 0},
+					IsNew: true,
+				},
+			},
+		},
+		{
+			name: "variable 2",
+			in:   "var a = 0.0",
+			want: []Statement{
+				Variable{
+					Name: Identifier{
+						Token:          NewToken(IDENT, "a"),
+						IsFunctionCall: false,
+					},
+					Value: Float{Value: 0.0},
+					IsNew: true,
+				},
+			},
+		},
+		{
+			name: "variable 3",
+			in:   "var a = \"Hello World!\"",
+			want: []Statement{
+				Variable{
+					Name: Identifier{
+						Token:          NewToken(IDENT, "a"),
+						IsFunctionCall: false,
+					},
+					Value: String{Value: "Hello World!"},
+					IsNew: true,
+				},
+			},
+		},
+		{
+			name: "variable 4",
+			in:   "global a = 1",
+			want: []Statement{
+				Variable{
+					Name: Identifier{
+						Token:          NewToken(IDENT, "a"),
+						IsFunctionCall: false,
+					},
+					Value:    Integer{Value: 1},
+					IsGlobal: true,
+				},
+			},
+		},
+		{
+			name: "variable 5",
+			in:   "var a = b = c + 1",
+			want: []Statement{
+				Variable{
+					Name: Identifier{
+						Token:          NewToken(IDENT, "a"),
+						IsFunctionCall: false,
+					},
+					Chain: []Identifier{
+						{
+							Token:          NewToken(IDENT, "b"),
+							IsFunctionCall: false,
+						},
+					},
+					Value: BinaryOperation{
+						Token: NewToken(PLUS, "+"),
+						Left: Identifier{
+							Token:          NewToken(IDENT, "c"),
+							IsFunctionCall: false,
+						},
+						Right: Integer{Value: 1},
+					},
+					IsNew: true,
+				},
+			},
+		},
+		{
+			name: "condition 1",
+			in:   "if true {}",
+			want: []Statement{
+				If{
+					Condition:   Boolean{Value: true},
+					Consequence: Block{},
+				},
+			},
+		},
+		{
+			name: "condition 2",
+			in:   "if 1 == 2 {} else {}",
+			want: []Statement{
+				If{
+					Condition: BinaryOperation{
+						Token: NewToken(EQ, "=="),
+						Left:  Integer{Value: 1},
+						Right: Integer{Value: 2},
+					},
+					Consequence: Block{},
+					Alternative: &Block{},
+				},
+			},
+		},
+		{
+			name: "while 1",
+			in:   "while true {}",
+			want: []Statement{
+				While{
+					Condition:   Boolean{Value: true},
+					Consequence: Block{},
+				},
+			},
+		},
+		{
+			name: "while 2",
+			in:   "while a == 1 {}",
+			want: []Statement{
+				While{
+					Condition: BinaryOperation{
+						Token: NewToken(EQ, "=="),
+						Left: Identifier{
+							Token:          NewToken(IDENT, "a"),
+							IsFunctionCall: false,
+						},
+						Right: Integer{Value: 1},
+					},
+					Consequence: Block{},
+				},
+			},
+		},
+		{
+			name: "for 1",
+			in:   "for k, v in \"Hello World!\" {}",
+			want: []Statement{
+				For{
+					Key: Identifier{
+						Token:          NewToken(IDENT, "k"),
+						IsFunctionCall: false,
+					},
+					Value: Identifier{
+						Token:          NewToken(IDENT, "v"),
+						IsFunctionCall: false,
+					},
+					Condition:   String{Value: "Hello World!"},
+					Consequence: Block{},
+				},
+			},
+		},
+		{
+			name: "for 2",
+			in:   "for k, v in [0.1, 0.2] {}",
+			want: []Statement{
+				For{
+					Key: Identifier{
+						Token:          NewToken(IDENT, "k"),
+						IsFunctionCall: false,
+					},
+					Value: Identifier{
+						Token:          NewToken(IDENT, "v"),
+						IsFunctionCall: false,
+					},
+					Condition: Array{
+						Items: []Expression{
+							Float{Value: 0.1},
+							Float{Value: 0.2},
+						},
+					},
+					Consequence: Block{},
+				},
+			},
+		},
+		{
+			name: "for 3",
+			in:   "for k, v in {\"one\": 1} {}",
+			want: []Statement{
+				For{
+					Key: Identifier{
+						Token:          NewToken(IDENT, "k"),
+						IsFunctionCall: false,
+					},
+					Value: Identifier{
+						Token:          NewToken(IDENT, "v"),
+						IsFunctionCall: false,
+					},
+					Condition: Map{
+						Items: []MapItem{
+							{Key: String{Value: "one"}, Value: Integer{Value: 1}},
+						},
+					},
+					Consequence: Block{},
+				},
+			},
+		},
+		{
+			name: "function 1",
+			in:   "fn sum(a, b) { return a + b }",
+			want: []Statement{
+				Function{
+					Name: Identifier{
+						Token:          NewToken(IDENT, "sum"),
+						IsFunctionCall: true,
+					},
+					Parameters: []Identifier{
+						{
+							Token:          NewToken(IDENT, "a"),
+							IsFunctionCall: false,
+						},
+						{
+							Token:          NewToken(IDENT, "b"),
+							IsFunctionCall: false,
+						},
+					},
+					Body: Block{
+						Statements: []Statement{
+							Return{
+								Value: BinaryOperation{
+									Token: NewToken(PLUS, "+"),
+									Left: Identifier{
+										Token:          NewToken(IDENT, "a"),
+										IsFunctionCall: false,
+									},
+									Right: Identifier{
+										Token:          NewToken(IDENT, "b"),
+										IsFunctionCall: false,
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "block 1",
+			in:   "{ xy = 0 if true {}}",
+			want: []Statement{
+				Block{
+					Statements: []Statement{
+						Variable{
+							Name: Identifier{
+								Token:          NewToken(IDENT, "xy"),
+								IsFunctionCall: false,
+							},
+							Value: Integer{Value: 0},
+						},
+						If{
+							Condition:   Boolean{Value: true},
+							Consequence: Block{},
+						},
+					},
+				},
+			},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			lexer := NewLexer(tc.in)
+			parser := NewParser(lexer)
+			statements := parser.Parse()
+			for _, want := range tc.want {
+				got := <-statements
+				assert.Equal(t, want, withoutPositions(got))
+			}
+		})
+	}
+}
+
+// withoutPositions returns a copy of the node with the positions of its tokens zeroed, so
+// that the trees above can be written without counting columns.
+func withoutPositions(node Statement) Statement {
+	if node == nil {
+		return nil
+	}
+	return clearPositions(reflect.ValueOf(node)).Interface()
+}
+
+func clearPositions(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		cleared := reflect.New(v.Type()).Elem()
+		cleared.Set(clearPositions(v.Elem()))
+		return cleared
+	case reflect.Pointer:
+		if v.IsNil() {
+			return v
+		}
+		cleared := reflect.New(v.Type().Elem())
+		cleared.Elem().Set(clearPositions(v.Elem()))
+		return cleared
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		cleared := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			cleared.Index(i).Set(clearPositions(v.Index(i)))
+		}
+		return cleared
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		cleared := reflect.MakeMapWithSize(v.Type(), v.Len())
+		for _, key := range v.MapKeys() {
+			cleared.SetMapIndex(clearPositions(key), clearPositions(v.MapIndex(key)))
+		}
+		return cleared
+	case reflect.Struct:
+		cleared := reflect.New(v.Type()).Elem()
+		cleared.Set(v)
+		if token, ok := v.Interface().(Token); ok {
+			token.Line, token.Column, token.NewlineBefore = 0, 0, false
+			cleared.Set(reflect.ValueOf(token))
+			return cleared
+		}
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() {
+				cleared.Field(i).Set(clearPositions(v.Field(i)))
+			}
+		}
+		return cleared
+	default:
+		return v
+	}
+}
+
+func TestParserEmptyProgram(t *testing.T) {
+	for _, in := range []string{"", " \t\r\n", "# comment", "# one\n\n# two\n"} {
+		lexer := NewLexer(in)
+		parser := NewParser(lexer)
+		statements := parser.Parse()
+		_, ok := <-statements
+		assert.False(t, ok, "expected no statements for %q", in)
+		assert.Empty(t, parser.Errors())
+	}
+}
+
+func TestParserStopsAtError(t *testing.T) {
+	lexer := NewLexer("var a = 1\n)\nvar b = 2")
+	parser := NewParser(lexer)
+	var got []Statement
+	for statement := range parser.Parse() {
+		assert.NotNil(t, statement)
+		got = append(got, statement)
+	}
+	assert.Len(t, got, 1)
+	assert.Len(t, parser.Errors(), 1)
+}
+
+func TestParserErrorLeavesNoGoroutines(t *testing.T) {
+	before := runtime.NumGoroutine()
+	for i := 0; i < 200; i++ {
+		parser := NewParser(NewLexer("var a = )\nvar b = 2\nvar c = 3"))
+		for range parser.Parse() {
+		}
+	}
+	assert.Eventually(t, func() bool {
+		return runtime.NumGoroutine() <= before
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestParserAssignmentInCondition(t *testing.T) {
+	tt := []struct {
+		in   string
+		want string
+	}{
+		{in: "if x = 5 {}", want: "1:6: unexpected = in condition, did you mean ==?"},
+		{in: "while x = 5 {}", want: "1:9: unexpected = in condition, did you mean ==?"},
+		{in: "if true { if x = 5 { y = 1 } }", want: "1:16: unexpected = in condition, did you mean ==?"},
+	}
+	for _, tc := range tt {
+		lexer := NewLexer(tc.in)
+		parser := NewParser(lexer)
+		for range parser.Parse() {
+		}
+		assert.Equal(t, []error{fmt.Errorf(tc.want)}, parser.Errors(), tc.in)
+	}
+}
+
+func TestParserUnknownType(t *testing.T) {
+	parser := NewParser(NewLexer("typematch x { number: {} }"))
+	for range parser.Parse() {
+	}
+	assert.Equal(t, []error{fmt.Errorf("1:15: unknown type number in typematch")}, parser.Errors())
+}
+
+func TestParserBreakOutsideLoop(t *testing.T) {
+	for _, in := range []string{"break", "if true { continue }", "while true { fn f() { break } }"} {
+		parser := NewParser(NewLexer(in))
+		for range parser.Parse() {
+		}
+		assert.Len(t, parser.Errors(), 1, in)
+	}
+	parser := NewParser(NewLexer("continue"))
+	for range parser.Parse() {
+	}
+	assert.Equal(t, []error{fmt.Errorf("1:1: continue outside a loop")}, parser.Errors())
+}
+
+func TestParserDeferOutsideFunction(t *testing.T) {
+	parser := NewParser(NewLexer("while true { defer println(1) }"))
+	for range parser.Parse() {
+	}
+	assert.Equal(t, []error{fmt.Errorf("1:14: defer outside a function")}, parser.Errors())
+}
+
+func TestParserErrorsInsteadOfExiting(t *testing.T) {
+	tt := []struct {
+		in   string
+		want string
+	}{
+		{in: "fn 1(a) {}", want: "1:4: expected IDENT, got INT instead"},
+		{in: "outer: if true {}", want: "1:8: expected one of [WHILE FOR], got IF instead"},
+		{in: "a @ b", want: "1:3: invalid token @"},
+		{in: "a\n/* b\nc", want: "2:1: unterminated block comment"},
+		{in: "if true {} else 1", want: "1:17: expected {, got INT instead"},
+		{in: "var a = 1\n\nvar = 2", want: "3:5: expected IDENT, got = instead"},
+		{in: "var a += 1", want: "1:7: expected =, got += instead"},
+	}
+	for _, tc := range tt {
+		parser := NewParser(NewLexer(tc.in))
+		for range parser.Parse() {
+		}
+		assert.Equal(t, []error{fmt.Errorf(tc.want)}, parser.Errors(), tc.in)
+	}
+}
+
+// Every prefix of a program cut short should stop the parser with an error, rather than
+// hang it or crash it.
+func TestParserTruncatedInput(t *testing.T) {
+	program := `fn f(a, b) { var m = {"k": [a, b[0]]} for i = 0; i < 2; i = i + 1 { print(m["k"], sep=", ") } }`
+	for i := range program {
+		parser := NewParser(NewLexer(program[:i]))
+		done := make(chan bool)
+		go func() {
+			for range parser.Parse() {
+			}
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatalf("parser hangs on %q", program[:i])
+		}
+	}
+}
+
+func TestParserUnterminatedString(t *testing.T) {
+	parser := NewParser(NewLexer(`println("abc)`))
+	for range parser.Parse() {
+	}
+	assert.Equal(t, []error{fmt.Errorf(`1:9: invalid token "abc)`)}, parser.Errors())
+}
+
+func TestPrecedence(t *testing.T) {
+	tt := []struct {
+		in   string
+		want string
+	}{
+		{in: "2 + 3 * 4 - 1", want: "((2 + (3 * 4)) - 1)"},
+		{in: "1 + 7 % 4 * 2", want: "(1 + ((7 % 4) * 2))"},
+		{in: "0..n + 1 == a", want: "((0..(n + 1)) == a)"},
+		{in: "1 - 2 - 3", want: "((1 - 2) - 3)"},
+		{in: "8 / 4 / 2", want: "((8 / 4) / 2)"},
+		{in: "8 / 4 * 2", want: "((8 / 4) * 2)"},
+		{in: "(1 + 2) * 3", want: "((1 + 2) * 3)"},
+		{in: "-(1 + 2)", want: "(-(1 + 2))"},
+		{in: "-a * b", want: "((-a) * b)"},
+		{in: "!a == b", want: "((!a) == b)"},
+		{in: "a + b < c * d", want: "((a + b) < (c * d))"},
+		{in: "a < b == c < d", want: "((a < b) == (c < d))"},
+		{in: "a and b or c", want: "((a and b) or c)"},
+		{in: "a or b and c", want: "(a or (b and c))"},
+		{in: "a or b or c", want: "((a or b) or c)"},
+		{in: "a and b and c", want: "((a and b) and c)"},
+		{in: "a == b and c == d", want: "((a == b) and (c == d))"},
+		{in: "a != b or c < d", want: "((a != b) or (c < d))"},
+		{in: "a == b or c == d and e != f", want: "((a == b) or ((c == d) and (e != f)))"},
+		{in: "f(1 + 2) * a[1 - 1]", want: "(f((1 + 2)) * a[(1 - 1)])"},
+	}
+	for _, tc := range tt {
+		t.Run(tc.in, func(t *testing.T) {
+			lexer := NewLexer(tc.in)
+			parser := NewParser(lexer)
+			got := Dump(<-parser.Parse())
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestDump(t *testing.T) {
+	tt := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			name: "precedence",
+			in:   "1 + 2 * 3 - -x",
+			want: "((1 + (2 * 3)) - (-x))",
+		},
+		{
+			name: "suffixes after literals",
+			in:   "var x = [1, 2][0] + \"ab\"[0:1] + (f)(1) + {\"k\": 1}[\"k\"]\nprintln(\"ab\"[0])",
+			want: "var x = ((([1, 2][0] + \"ab\"[0:1]) + f(1)) + {\"k\": 1}[\"k\"])\nprintln(\"ab\"[0])",
+		},
+		{
+			name: "suffixes on the same line only",
+			in:   "var x = a\n[1, 2]\n(b)",
+			want: "var x = a\n[1, 2]\nb",
+		},
+		{
+			name: "suffix continued on the next line",
+			in:   "var x = a \\\n[0]",
+			want: "var x = a[0]",
+		},
+		{
+			name: "map literal in source order",
+			in:   `var m = {"b": 1, "a": [fn(x) { return x }], f(1): 2}`,
+			want: "var m = {\"b\": 1, \"a\": [fn(x) {\n    return x\n}], f(1): 2}",
+		},
+		{
+			name: "chained unary operators",
+			in:   "!!a == b != !(c == d) - --1",
+			want: "(((!(!a)) == b) != ((!(c == d)) - (-(-1))))",
+		},
+		{
+			name: "bitwise precedence",
+			in:   "a | b ^ c & d << 1 + 1 == e",
+			want: "((a | (b ^ (c & (d << (1 + 1))))) == e)",
+		},
+		{
+			name: "literals",
+			in:   `[true, 1.0, "a", {"k": v}, data[0], len(s)]`,
+			want: `[true, 1.0, "a", {"k": v}, data[0], len(s)]`,
+		},
+		{
+			name: "function",
+			in:   "fn sum(a, b) { if a > b { return a } else { println(b) } return a + b }",
+			want: "fn sum(a, b) {\n    if (a > b) {\n        return a\n    } else {\n        println(b)\n    }\n    return (a + b)\n}",
+		},
+		{
+			name: "loops",
+			in:   "while i < 3 { i = i + 1 } for k in [] {}",
+			want: "while (i < 3) {\n    i = (i + 1)\n}\nfor k in [] {}",
+		},
+		{
+			name: "binary expression across lines",
+			in:   "var a = 1 +\n    2 *\n    3\nvar b = 1\n    + 2",
+			want: "var a = (1 + (2 * 3))\nvar b = (1 + 2)",
+		},
+		{
+			name: "call across lines",
+			in:   "sum(\n    1,\n    [2,\n     3]\n)\nprintln(x,\n    y)",
+			want: "sum(1, [2, 3])\nprintln(x, y)",
+		},
+		{
+			name: "line continuation",
+			in:   "var a = 1 \\\n    + 2\nsum(a, \\\r\n    b)",
+			want: "var a = (1 + 2)\nsum(a, b)",
+		},
+		{
+			name: "typematch",
+			in:   "typematch x { int, float: { println(x) } nil: {} else: { return 0 } }",
+			want: "typematch x {\n    int, float: {\n        println(x)\n    }\n    nil: {}\n    else: {\n        return 0\n    }\n}",
+		},
+		{
+			name: "classic for",
+			in:   "for i = 0; i < 3; i = i + 1 { if i == 1 { continue } break }\nfor ; ; { break }",
+			want: "for var i = 0; (i < 3); i = (i + 1) {\n    if (i == 1) {\n        continue\n    }\n    break\n}\nfor ; ; {\n    break\n}",
+		},
+		{
+			name: "labeled loops",
+			in:   "outer: while true { inner: for k in x { continue outer break inner break } }",
+			want: "outer: while true {\n    inner: for k in x {\n        continue outer\n        break inner\n        break\n    }\n}",
+		},
+		{
+			name: "break before a name that isn't a label",
+			in:   "while true { break x }",
+			want: "while true {\n    break\n    x\n}",
+		},
+		{
+			name: "anonymous functions",
+			in:   "var f = fn(a, b) { return a + b }\nfn(x) { return x }(1)\nfn named() {}",
+			want: "var f = fn(a, b) {\n    return (a + b)\n}\nfn(x) {\n    return x\n}(1)\nfn named() {}",
+		},
+		{
+			name: "else if",
+			in:   "if a { 1 } else if b { 2 } else if c {} else { 3 }",
+			want: "if a {\n    1\n} else if b {\n    2\n} else if c {} else {\n    3\n}",
+		},
+		{
+			name: "index assignment",
+			in:   "a[0] = 1 + 2\nm[\"k\"][i + 1] = [a[0]]\na[0]",
+			want: "a[0] = (1 + 2)\nm[\"k\"][(i + 1)] = [a[0]]\na[0]",
+		},
+		{
+			name: "bare return",
+			in:   "fn f(a) { if a { return } return\nvar b = 1 }",
+			want: "fn f(a) {\n    if a {\n        return\n    }\n    return\n    var b = 1\n}",
+		},
+		{
+			name: "bare return before a line",
+			in:   "fn f() { return\nx\nreturn x + 1\n}",
+			want: "fn f() {\n    return\n    x\n    return (x + 1)\n}",
+		},
+		{
+			name: "return continued on the next line",
+			in:   "fn f() { return \\\nx + 1\n}",
+			want: "fn f() {\n    return (x + 1)\n}",
+		},
+		{
+			name: "compound assignment",
+			in:   "x += 1 + 2\nx *= y",
+			want: "x = (x + (1 + 2))\nx = (x * y)",
+		},
+		{
+			name: "slice",
+			in:   "s[1:len(s) - 1]\ns[:2]\ns[2:]\ns[:]",
+			want: "s[1:(len(s) - 1)]\ns[:2]\ns[2:]\ns[:]",
+		},
+		{
+			name: "spread",
+			in:   "[1, ...rest, 5]\nsum(...[1, 2], 3)",
+			want: "[1, ...rest, 5]\nsum(...[1, 2], 3)",
+		},
+		{
+			name: "defer",
+			in:   "fn f() { defer close(file) return 1 }",
+			want: "fn f() {\n    defer close(file)\n    return 1\n}",
+		},
+		{
+			name: "chained variables",
+			in:   "var a = b = 0 a = b = 1 global a = b = 2",
+			want: "var a = b = 0\na = b = 1\nglobal a = b = 2",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			lexer := NewLexer(tc.in)
+			parser := NewParser(lexer)
+			var got []string
+			for statement := range parser.Parse() {
+				got = append(got, Dump(statement))
+			}
+			assert.Equal(t, tc.want, strings.Join(got, "\n"))
+		})
+	}
+}
@@ -0,0 +1,2957 @@
+// Evaluating is the process that defines how the programming language being interpreted works.
+// The statements are executed in the source language, which in this case it is Golang.
+
+package uni
+
+import (
+	"bufio"
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"math"
+	"math/bits"
+	"net/http"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Version is the version of the interpreter, which scripts can read with version().
+const Version = "0.1.0"
+
+// ************
+// ** Scope **
+// ************
+
+type Scope struct {
+	variables map[string]any
+	functions map[string]any
+	parent    *Scope
+	frozen    map[uintptr]any // the frozen containers, kept so their addresses aren't reused
+}
+
+func NewScope(scope *Scope) *Scope {
+	return &Scope{
+		variables: make(map[string]any),
+		functions: make(map[string]any),
+		parent:    scope,
+	}
+}
+
+func (s *Scope) GetVariable(identifier Identifier) (any, bool) {
+	variable, ok := s.variables[identifier.Token.Value]
+	if !ok && s.parent != nil {
+		variable, ok = s.parent.GetVariable(identifier)
+	}
+	return variable, ok
+}
+
+// SetVariable binds the identifier to the value, unless the identifier is _, in which case
+// the value is thrown away.
+func (s *Scope) SetVariable(identifier Identifier, value any) {
+	if identifier.Token.Value == "_" {
+		return
+	}
+	s.variables[identifier.Token.Value] = value
+}
+
+// SetExistingVariable rebinds the variable in the nearest scope that has it, leaving any
+// variable it shadows alone. It reports whether one was found, which _ always is.
+func (s *Scope) SetExistingVariable(identifier Identifier, value any) bool {
+	if identifier.Token.Value == "_" {
+		return true
+	}
+	for scope := s; scope != nil; scope = scope.parent {
+		if _, ok := scope.variables[identifier.Token.Value]; ok {
+			scope.SetVariable(identifier, value)
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Scope) GetFunction(identifier Identifier) (any, bool) {
+	function, ok := s.functions[identifier.Token.Value]
+	if !ok && s.parent != nil {
+		function, ok = s.parent.GetFunction(identifier)
+	}
+	return function, ok
+}
+
+func (s *Scope) SetFunction(identifier Identifier, value any) {
+	s.functions[identifier.Token.Value] = value
+}
+
+// RegisterNative defines a function written in Go, which scripts call like any other. It
+// gets the evaluated arguments as Uni values, its result is converted with FromGo, and an
+// error it returns stops the program as a runtime error.
+func (s *Scope) RegisterNative(name string, fn func(args []any) (any, error)) {
+	s.functions[name] = Native{Name: name, Function: fn}
+}
+
+// Lookup finds what the identifier names in the nearest scope that defines it, as either a
+// variable or a function, so that a local variable shadows an outer function and a local
+// function shadows an outer variable.
+func (s *Scope) Lookup(identifier Identifier) (any, bool) {
+	for scope := s; scope != nil; scope = scope.parent {
+		if variable, ok := scope.variables[identifier.Token.Value]; ok {
+			return variable, true
+		}
+		if function, ok := scope.functions[identifier.Token.Value]; ok {
+			return function, true
+		}
+	}
+	return nil, false
+}
+
+func (s *Scope) GetParent() *Scope {
+	return s.parent
+}
+
+func (s *Scope) GetRoot() *Scope {
+	root := s
+	for root.parent != nil {
+		root = root.parent
+	}
+	return root
+}
+
+// Inject binds each of the Go values to a variable, converting them with FromGo, so a
+// host can hand data to a script.
+func (s *Scope) Inject(vars map[string]any) {
+	for name, value := range vars {
+		s.variables[name] = FromGo(value)
+	}
+}
+
+// Freeze makes an array or a map immutable, along with the arrays and maps inside it. The
+// frozen values are kept by the root scope, so they stay frozen for as long as it lives,
+// and no other container can take the address of one.
+func (s *Scope) Freeze(value any) {
+	root := s.GetRoot()
+	if root.frozen == nil {
+		root.frozen = make(map[uintptr]any)
+	}
+	root.freeze(value)
+}
+
+func (s *Scope) freeze(value any) {
+	pointer, ok := containerPointer(value)
+	if _, frozen := s.frozen[pointer]; !ok || frozen {
+		return
+	}
+	s.frozen[pointer] = value
+	switch v := value.(type) {
+	case []any:
+		for _, item := range v {
+			s.freeze(item)
+		}
+	case map[any]any:
+		for _, item := range v {
+			s.freeze(item)
+		}
+	}
+}
+
+func (s *Scope) IsFrozen(value any) bool {
+	pointer, ok := containerPointer(value)
+	if !ok {
+		return false
+	}
+	_, frozen := s.GetRoot().frozen[pointer]
+	return frozen
+}
+
+// containerPointer identifies an array or a map. The arrays made by the evaluator always
+// have room for an item, see newArray, so even an empty one has an address of its own.
+// An array without any room can't be told apart from other empty arrays.
+func containerPointer(value any) (uintptr, bool) {
+	switch v := value.(type) {
+	case []any:
+		if cap(v) == 0 {
+			return 0, false
+		}
+		return reflect.ValueOf(v).Pointer(), true
+	case map[any]any:
+		return reflect.ValueOf(v).Pointer(), true
+	default:
+		return 0, false
+	}
+}
+
+// newArray makes an array with the given length and capacity. It always allocates, even
+// for an empty array, so that every array can be frozen on its own.
+func newArray(length, capacity int) []any {
+	if capacity == 0 {
+		capacity = 1
+	}
+	return make([]any, length, capacity)
+}
+
+// Variables returns the variables defined in the scope itself, keyed by name, leaving out
+// the functions and the variables of its parents.
+func (s *Scope) Variables() map[string]any {
+	variables := make(map[string]any, len(s.variables))
+	for name, variable := range s.variables {
+		variables[name] = variable
+	}
+	return variables
+}
+
+// Snapshot returns every variable and function visible from the scope, keyed by name.
+func (s *Scope) Snapshot() map[string]any {
+	snapshot := make(map[string]any)
+	if s.parent != nil {
+		snapshot = s.parent.Snapshot()
+	}
+	for name, function := range s.functions {
+		snapshot[name] = function
+	}
+	for name, variable := range s.variables {
+		snapshot[name] = variable
+	}
+	return snapshot
+}
+
+// ***************
+// ** Evaluator **
+// ***************
+
+type Evaluator struct {
+	parser    *Parser
+	ctx       context.Context
+	in        *bufio.Reader
+	out       io.Writer
+	errOut    io.Writer
+	function  *Function
+	deferred  []deferred // of the function being called
+	tailCalls bool
+	tests     *TestResults
+	profile   *Profile
+	precision int
+	root      *Scope
+	regexps   map[string]*regexp.Regexp
+	sandbox   bool
+	depth     int // of the user function calls being evaluated
+	maxDepth  int
+}
+
+// DefaultMaxDepth is how deeply user functions can call each other by default.
+const DefaultMaxDepth = 10000
+
+// stdin is shared by the evaluators reading from the process's stdin, so that what one of
+// them has buffered isn't lost to the next.
+var stdin = bufio.NewReader(os.Stdin)
+
+func NewEvaluator(parser *Parser) *Evaluator {
+	return &Evaluator{
+		parser:    parser,
+		ctx:       context.Background(),
+		in:        stdin,
+		out:       os.Stdout,
+		errOut:    os.Stderr,
+		tailCalls: true,
+		maxDepth:  DefaultMaxDepth,
+	}
+}
+
+// SetInput sets where input reads lines from, which is stdin by default.
+func (e *Evaluator) SetInput(in io.Reader) {
+	e.in = bufio.NewReader(in)
+}
+
+// SetOutput sets where print and println write to, which is stdout by default.
+func (e *Evaluator) SetOutput(out io.Writer) {
+	e.out = out
+}
+
+// SetErrorOutput sets where the error that stops the program is written to, which is
+// stderr by default, so a host can tell it apart from what the program prints.
+func (e *Evaluator) SetErrorOutput(errOut io.Writer) {
+	e.errOut = errOut
+}
+
+// SetTailCalls turns the tail-call optimization on or off. When it's on, which is the
+// default, `return f(...)` inside f reuses the current call instead of nesting a new one.
+func (e *Evaluator) SetTailCalls(enabled bool) {
+	e.tailCalls = enabled
+}
+
+// SetTestResults makes a failing assert count as a failed test in results instead of
+// stopping the program, so a test script runs to the end and reports every failure.
+func (e *Evaluator) SetTestResults(results *TestResults) {
+	e.tests = results
+}
+
+// SetPrecision rounds the floats that print and str show to the given number of
+// significant digits. The values themselves keep their full precision. 0, the default,
+// shows floats in full.
+func (e *Evaluator) SetPrecision(digits int) {
+	e.precision = digits
+}
+
+// SetSandbox turns the sandbox on or off. A sandboxed script can compute, but the builtins
+// that reach outside the interpreter, to files, the environment, or the network, fail with
+// a runtime error.
+func (e *Evaluator) SetSandbox(enabled bool) {
+	e.sandbox = enabled
+}
+
+// SetMaxDepth sets how deeply user functions can call each other before the call that
+// goes too deep fails with a runtime error, instead of overflowing the stack. Tail calls
+// don't count, since they reuse the call they replace. 0 means there is no limit.
+func (e *Evaluator) SetMaxDepth(depth int) {
+	e.maxDepth = depth
+}
+
+// SetProfile makes the evaluator count every node it evaluates, and the time spent on it,
+// in profile.
+func (e *Evaluator) SetProfile(profile *Profile) {
+	e.profile = profile
+}
+
+func (e *Evaluator) Eval(scope *Scope) any {
+	return e.EvalWithContext(context.Background(), scope)
+}
+
+// EvalWithContext evaluates the program until it finishes or ctx is done, in which case
+// the context's error is returned as the value. A syntax error is returned the same way.
+// Either way, the error is also written to the error output.
+func (e *Evaluator) EvalWithContext(ctx context.Context, scope *Scope) any {
+	result := e.evalProgram(ctx, scope)
+	if err, ok := result.(error); ok {
+		fmt.Fprintln(e.errOut, err)
+	}
+	return result
+}
+
+func (e *Evaluator) evalProgram(ctx context.Context, scope *Scope) any {
+	e.ctx = ctx
+	e.root = scope.GetRoot()
+	var value any
+	statements := e.parser.Parse()
+	for statement := range statements {
+		if err := e.ctx.Err(); err != nil {
+			go drain(statements)
+			return err
+		}
+		value = e.evalStatement(statement, scope)
+		if err, ok := value.(RuntimeError); ok {
+			go drain(statements)
+			return err
+		}
+		if result, ok := value.(ReturnValue); ok {
+			go drain(statements)
+			return result.Value
+		}
+	}
+	if err := e.ctx.Err(); err != nil {
+		return err
+	}
+	if errs := e.parser.Errors(); len(errs) > 0 {
+		return errs[0]
+	}
+	return value
+}
+
+// drain lets the parser run to completion when evaluation stops early, so its goroutine
+// doesn't block forever on the unread channel.
+func drain(statements chan Statement) {
+	for range statements {
+	}
+}
+
+// RuntimeError is the value of anything that went wrong while running a program that
+// parsed. When a statement evaluates to one, the evaluation stops and the error is
+// returned. Line and Column locate the expression it came from, and are 0 until the
+// evaluator fills them in on its way out of it.
+type RuntimeError struct {
+	Message string
+	Line    int
+	Column  int
+}
+
+func NewRuntimeError(format string, args ...any) RuntimeError {
+	return RuntimeError{Message: fmt.Sprintf(format, args...)}
+}
+
+func (e RuntimeError) Error() string {
+	if e.Line == 0 {
+		return "runtime error: " + e.Message
+	}
+	return fmt.Sprintf("%d:%d: runtime error: %s", e.Line, e.Column, e.Message)
+}
+
+// locate gives a RuntimeError without a position the position of the node it came out of.
+// The innermost node with a position is the first to see the error, so it's the one kept.
+func locate(result any, node any) any {
+	err, ok := result.(RuntimeError)
+	if !ok || err.Line != 0 {
+		return result
+	}
+	if token, ok := position(node); ok {
+		err.Line, err.Column = token.Line, token.Column
+	}
+	return err
+}
+
+// position returns the token that best locates the node, if the node has one.
+func position(node any) (Token, bool) {
+	switch n := node.(type) {
+	case Identifier:
+		return n.Token, true
+	case UnaryOperation:
+		return n.Token, true
+	case BinaryOperation:
+		return n.Token, true
+	case Call:
+		return position(n.Function)
+	case Index:
+		return position(n.Subject)
+	case Slice:
+		return position(n.Subject)
+	case Spread:
+		return n.Token, true
+	default:
+		return Token{}, false
+	}
+}
+
+// Profile tallies how many times each kind of node is evaluated and how long it takes.
+// The time of a node includes the time of the nodes inside it.
+type Profile struct {
+	Counts    map[string]int
+	Durations map[string]time.Duration
+}
+
+func NewProfile() *Profile {
+	return &Profile{Counts: map[string]int{}, Durations: map[string]time.Duration{}}
+}
+
+func (p *Profile) record(node any, start time.Time) {
+	name := reflect.TypeOf(node).Name()
+	p.Counts[name]++
+	p.Durations[name] += time.Since(start)
+}
+
+// Report writes one line per kind of node, the slowest first.
+func (p *Profile) Report(out io.Writer) {
+	names := make([]string, 0, len(p.Counts))
+	for name := range p.Counts {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if p.Durations[names[i]] != p.Durations[names[j]] {
+			return p.Durations[names[i]] > p.Durations[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	fmt.Fprintf(out, "%-16s %10s %14s\n", "node", "count", "time")
+	for _, name := range names {
+		fmt.Fprintf(out, "%-16s %10d %14s\n", name, p.Counts[name], p.Durations[name])
+	}
+}
+
+// TestResults counts the assertions checked while running a script with `uni test`.
+type TestResults struct {
+	Passed   int
+	Failed   int
+	Failures []string
+}
+
+// ****************
+// ** Statements **
+// ****************
+
+func (e *Evaluator) evalStatement(statement Statement, scope *Scope) any {
+	if e.profile != nil {
+		switch statement.(type) {
+		case Variable, IndexAssign, If, While, For, ForClassic, Function, Return, TypeMatch, Block:
+			defer e.profile.record(statement, time.Now())
+		}
+	}
+	switch typedStatement := statement.(type) {
+	case Variable:
+		return e.evalVariable(typedStatement, scope)
+	case IndexAssign:
+		return locate(e.evalIndexAssign(typedStatement, scope), typedStatement.Target)
+	case If:
+		return e.evalIf(typedStatement, scope)
+	case While:
+		return e.evalWhile(typedStatement, scope)
+	case For:
+		return e.evalFor(typedStatement, scope)
+	case ForClassic:
+		return e.evalForClassic(typedStatement, scope)
+	case Defer:
+		e.deferred = append(e.deferred, deferred{value: typedStatement.Value, scope: scope})
+		return nil
+	case Break:
+		return breakSignal{label: typedStatement.Label.Token.Value}
+	case Continue:
+		return continueSignal{label: typedStatement.Label.Token.Value}
+	case Function:
+		return e.evalFunction(typedStatement, scope)
+	case Return:
+		return e.evalReturn(typedStatement, scope)
+	case TypeMatch:
+		return e.evalTypeMatch(typedStatement, scope)
+	case Block:
+		return e.evalBlock(typedStatement, NewScope(scope))
+	default:
+		return e.evalExpression(typedStatement, scope)
+	}
+}
+
+func (e *Evaluator) evalVariable(in Variable, scope *Scope) any {
+	value := e.evalExpression(in.Value, scope)
+	if err, ok := value.(error); ok {
+		return err
+	}
+	if err := e.assign(in, in.Name, value, scope); err != nil {
+		return err
+	}
+	for _, name := range in.Chain {
+		if err := e.assign(in, name, value, scope); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// assign binds the name to the value. Without var or global, the variable must already
+// exist, so a misspelled name is an error rather than a variable nothing reads.
+func (e *Evaluator) assign(in Variable, name Identifier, value any, scope *Scope) error {
+	if in.IsNew {
+		scope.SetVariable(name, value)
+		return nil
+	}
+	if in.IsGlobal {
+		scope.GetRoot().SetVariable(name, value)
+		return nil
+	}
+	if !scope.SetExistingVariable(name, value) {
+		return locate(NewRuntimeError("undefined variable %s", name.Token.Value), name).(error)
+	}
+	return nil
+}
+
+func (e *Evaluator) evalIndexAssign(in IndexAssign, scope *Scope) any {
+	subject := e.evalExpression(in.Target.Subject, scope)
+	index := e.evalExpression(in.Target.Index, scope)
+	value := e.evalExpression(in.Value, scope)
+	for _, operand := range []any{subject, index, value} {
+		if err, ok := operand.(error); ok {
+			return err
+		}
+	}
+	switch subject := subject.(type) {
+	case []any:
+		i, err := checkIndex(index, subject, len(subject))
+		if err != nil {
+			return err
+		}
+		if e.root.IsFrozen(subject) {
+			return NewRuntimeError("cannot change a frozen array")
+		}
+		subject[i] = value
+	case map[any]any:
+		if !isHashable(index) {
+			return NewRuntimeError("cannot use %s as a map key", typeName(index))
+		}
+		if e.root.IsFrozen(subject) {
+			return NewRuntimeError("cannot change a frozen map")
+		}
+		subject[index] = value
+	default:
+		return NewRuntimeError("cannot assign to an index of %s", typeName(subject))
+	}
+	return nil
+}
+
+// isHashable reports whether the value can be a map key. Arrays, maps, and functions
+// can't, since Go can't hash them.
+func isHashable(value any) bool {
+	t := reflect.TypeOf(value)
+	return t == nil || t.Comparable()
+}
+
+func (e *Evaluator) evalIf(in If, scope *Scope) any {
+	condition, err := e.evalCondition("if", in.Condition, scope)
+	if err != nil {
+		return err
+	}
+	if condition {
+		return e.evalBlock(in.Consequence, NewScope(scope))
+	}
+	if in.Alternative != nil {
+		return e.evalBlock(*in.Alternative, NewScope(scope))
+	}
+	return nil
+}
+
+func (e *Evaluator) evalWhile(in While, scope *Scope) any {
+	for {
+		condition, err := e.evalCondition("while", in.Condition, scope)
+		if err != nil {
+			return err
+		}
+		if !condition {
+			return nil
+		}
+		if err := e.ctx.Err(); err != nil {
+			return err
+		}
+		newScope := NewScope(scope)
+		if result, stop := loopControl(e.evalBlock(in.Consequence, newScope), in.Label); stop {
+			return result
+		}
+	}
+}
+
+// evalCondition evaluates the condition of the statement named by keyword, which must be
+// a bool.
+func (e *Evaluator) evalCondition(keyword string, condition Expression, scope *Scope) (bool, error) {
+	switch value := e.evalExpression(condition, scope).(type) {
+	case bool:
+		return value, nil
+	case error:
+		return false, value
+	default:
+		err := NewRuntimeError("%s condition must be a bool, got %s", keyword, typeName(value))
+		return false, locate(err, condition).(error)
+	}
+}
+
+func (e *Evaluator) evalFor(in For, scope *Scope) any {
+	if bounds, ok := in.Condition.(Range); ok {
+		return e.evalForRange(in, bounds, scope)
+	}
+	switch subject := e.evalExpression(in.Condition, scope).(type) {
+	case string:
+		// ranging over a string yields byte offsets, so the runes are indexed instead to
+		// count characters the way array indices do
+		for key, value := range []rune(subject) {
+			if err := e.ctx.Err(); err != nil {
+				return err
+			}
+			newScope := NewScope(scope)
+			newScope.SetVariable(in.Key, int64(key))
+			newScope.SetVariable(in.Value, string(value))
+			if result, stop := loopControl(e.evalBlock(in.Consequence, newScope), in.Label); stop {
+				return result
+			}
+		}
+	case []any:
+		for key, value := range subject {
+			if err := e.ctx.Err(); err != nil {
+				return err
+			}
+			newScope := NewScope(scope)
+			newScope.SetVariable(in.Key, int64(key))
+			newScope.SetVariable(in.Value, value)
+			if result, stop := loopControl(e.evalBlock(in.Consequence, newScope), in.Label); stop {
+				return result
+			}
+		}
+	case map[any]any:
+		for key, value := range subject {
+			if err := e.ctx.Err(); err != nil {
+				return err
+			}
+			newScope := NewScope(scope)
+			newScope.SetVariable(in.Key, key)
+			newScope.SetVariable(in.Value, value)
+			if result, stop := loopControl(e.evalBlock(in.Consequence, newScope), in.Label); stop {
+				return result
+			}
+		}
+	case error:
+		return subject
+	default:
+		return locate(NewRuntimeError("cannot iterate over %s", typeName(subject)), in.Condition)
+	}
+	return nil
+}
+
+// evalForRange counts through the range without making an array of it. With one variable,
+// it's bound to the number, and with two, to the index and the number.
+func (e *Evaluator) evalForRange(in For, bounds Range, scope *Scope) any {
+	start, end, err := e.evalBounds(bounds, scope)
+	if err != nil {
+		return err
+	}
+	for i := start; i < end; i++ {
+		if err := e.ctx.Err(); err != nil {
+			return err
+		}
+		newScope := NewScope(scope)
+		if in.Value.Token.Value == "" {
+			newScope.SetVariable(in.Key, i)
+		} else {
+			newScope.SetVariable(in.Key, i-start)
+			newScope.SetVariable(in.Value, i)
+		}
+		if result, stop := loopControl(e.evalBlock(in.Consequence, newScope), in.Label); stop {
+			return result
+		}
+	}
+	return nil
+}
+
+func (e *Evaluator) evalForClassic(in ForClassic, scope *Scope) any {
+	loopScope := NewScope(scope)
+	if in.Init != nil {
+		if err, ok := e.evalStatement(in.Init, loopScope).(error); ok {
+			return err
+		}
+	}
+	for {
+		if err := e.ctx.Err(); err != nil {
+			return err
+		}
+		if in.Condition != nil {
+			condition, err := e.evalCondition("for", in.Condition, loopScope)
+			if err != nil {
+				return err
+			}
+			if !condition {
+				return nil
+			}
+		}
+		if result, stop := loopControl(e.evalBlock(in.Consequence, NewScope(loopScope)), in.Label); stop {
+			return result
+		}
+		// continue ends up here too, so the post step still runs and the loop advances
+		if in.Post != nil {
+			if err, ok := e.evalStatement(in.Post, loopScope).(error); ok {
+				return err
+			}
+		}
+	}
+}
+
+// loopControl decides what a loop does with the result of its body: it stops, returning
+// result, or goes on to the next iteration. A signal meant for an outer loop stops it
+// too, and is passed on.
+func loopControl(result any, label Identifier) (any, bool) {
+	switch signal := result.(type) {
+	case breakSignal:
+		if signal.label == "" || signal.label == label.Token.Value {
+			return nil, true
+		}
+		return signal, true
+	case continueSignal:
+		if signal.label == "" || signal.label == label.Token.Value {
+			return nil, false
+		}
+		return signal, true
+	case ReturnValue, tailCall, error:
+		return result, true
+	}
+	return nil, false
+}
+
+func (e *Evaluator) evalTypeMatch(in TypeMatch, scope *Scope) any {
+	subject := e.evalExpression(in.Subject, scope)
+	if err, ok := subject.(error); ok {
+		return err
+	}
+	name := typeName(subject)
+	for _, c := range in.Cases {
+		for _, t := range c.Types {
+			if t.Token.Value == name {
+				return e.evalBlock(c.Body, NewScope(scope))
+			}
+		}
+	}
+	if in.Else != nil {
+		return e.evalBlock(*in.Else, NewScope(scope))
+	}
+	return nil
+}
+
+func (e *Evaluator) evalFunction(in Function, scope *Scope) any {
+	if in.Name.Token.Value == "" {
+		return e.evalFunctionLiteral(in, scope)
+	}
+	scope.SetFunction(in.Name, in)
+	return nil
+}
+
+// evalFunctionLiteral makes the value of an anonymous function. Unlike a declared function,
+// which sees the scope it's called from, it keeps the scope it was made in, so it can use
+// the variables around it even after the function that made it has returned.
+func (e *Evaluator) evalFunctionLiteral(in Function, scope *Scope) any {
+	in.scope = scope
+	return in
+}
+
+func (e *Evaluator) evalReturn(in Return, scope *Scope) any {
+	if in.Value == nil {
+		return ReturnValue{}
+	}
+	if call, ok := in.Value.(Call); ok && e.isSelfCall(call, scope) {
+		arguments, err := e.evalItems(call.Arguments, scope)
+		if err != nil {
+			return err
+		}
+		return tailCall{arguments: arguments}
+	}
+	return ReturnValue{Value: e.evalExpression(in.Value, scope)}
+}
+
+// ReturnValue is what a return statement evaluates to. Blocks and loops pass it up to the
+// function call, which unwraps it, so a value that is returned can be told apart from the
+// value of any other statement.
+type ReturnValue struct {
+	Value any
+}
+
+// tailCall is returned in place of a value by `return f(...)` inside f. evalCall runs it
+// as the next iteration of a loop instead of recursing, so the stack doesn't grow.
+type tailCall struct {
+	arguments []any
+}
+
+// breakSignal and continueSignal are returned in place of a value by break and continue.
+// They pass up through blocks to the innermost loop, or the loop with their label, which
+// stops or moves on.
+type breakSignal struct {
+	label string
+}
+
+type continueSignal struct {
+	label string
+}
+
+func (e *Evaluator) isSelfCall(call Call, scope *Scope) bool {
+	if !e.tailCalls || e.function == nil {
+		return false
+	}
+	identifier, ok := call.Function.(Identifier)
+	if !ok {
+		return false
+	}
+	// the name alone isn't enough, since a nested function can shadow the one running
+	callee, found := scope.Lookup(identifier)
+	if !found {
+		return false
+	}
+	function, ok := callee.(Function)
+	return ok && sameFunction(function, *e.function)
+}
+
+// evalBlock runs the statements of a block, which is a statement itself and has no value.
+// It stops early to pass up a return, or an error.
+func (e *Evaluator) evalBlock(in Block, scope *Scope) any {
+	for _, statement := range in.Statements {
+		if statement == nil {
+			continue
+		}
+		if err := e.ctx.Err(); err != nil {
+			return err
+		}
+		switch result := e.evalStatement(statement, scope).(type) {
+		case breakSignal, continueSignal, ReturnValue, tailCall, error:
+			return result
+		}
+	}
+	return nil
+}
+
+// *****************
+// ** Expressions **
+// *****************
+
+func (e *Evaluator) evalExpression(expression Expression, scope *Scope) any {
+	if e.profile != nil {
+		defer e.profile.record(expression, time.Now())
+	}
+	return locate(e.evalNode(expression, scope), expression)
+}
+
+func (e *Evaluator) evalNode(expression Expression, scope *Scope) any {
+	switch typedExpression := expression.(type) {
+	case Boolean:
+		return e.evalBoolean(typedExpression, scope)
+	case Integer:
+		return e.evalInteger(typedExpression, scope)
+	case Float:
+		return e.evalFloat(typedExpression, scope)
+	case String:
+		return e.evalString(typedExpression, scope)
+	case Array:
+		return e.evalArray(typedExpression, scope)
+	case Map:
+		return e.evalMap(typedExpression, scope)
+	case Index:
+		return e.evalIndex(typedExpression, scope)
+	case Call:
+		return e.evalCall(typedExpression, scope)
+	case Identifier:
+		return e.evalIdentifier(typedExpression, scope)
+	case UnaryOperation:
+		return e.evalUnaryOperation(typedExpression, scope)
+	case BinaryOperation:
+		return e.evalBinaryOperation(typedExpression, scope)
+	case Slice:
+		return e.evalSlice(typedExpression, scope)
+	case Range:
+		return e.evalRange(typedExpression, scope)
+	case Function:
+		return e.evalFunctionLiteral(typedExpression, scope)
+	case Len:
+		return e.evalLen(typedExpression, scope)
+	case Print:
+		return e.evalPrint(typedExpression, scope)
+	default:
+		return nil
+	}
+}
+
+func (e *Evaluator) evalBoolean(in Boolean, _ *Scope) any {
+	return in.Value
+}
+
+func (e *Evaluator) evalInteger(in Integer, _ *Scope) any {
+	return in.Value
+}
+
+func (e *Evaluator) evalFloat(in Float, _ *Scope) any {
+	return in.Value
+}
+
+func (e *Evaluator) evalString(in String, _ *Scope) any {
+	return in.Value
+}
+
+func (e *Evaluator) evalArray(in Array, scope *Scope) any {
+	a, err := e.evalItems(in.Items, scope)
+	if err != nil {
+		return err
+	}
+	return a
+}
+
+func (e *Evaluator) evalMap(in Map, scope *Scope) any {
+	m := make(map[any]any, len(in.Items))
+	for _, item := range in.Items {
+		k, v := e.evalExpression(item.Key, scope), e.evalExpression(item.Value, scope)
+		for _, operand := range []any{k, v} {
+			if err, ok := operand.(error); ok {
+				return err
+			}
+		}
+		if !isHashable(k) {
+			return locate(NewRuntimeError("cannot use %s as a map key", typeName(k)), item.Key)
+		}
+		m[k] = v
+	}
+	return m
+}
+
+func (e *Evaluator) evalIndex(in Index, scope *Scope) any {
+	subject := e.evalExpression(in.Subject, scope)
+	index := e.evalExpression(in.Index, scope)
+	for _, operand := range []any{subject, index} {
+		if err, ok := operand.(error); ok {
+			return err
+		}
+	}
+	switch subject := subject.(type) {
+	case []any:
+		i, err := checkIndex(index, subject, len(subject))
+		if err != nil {
+			return err
+		}
+		return subject[i]
+	case string:
+		// indexed by character, like for loops and len, rather than by byte
+		runes := []rune(subject)
+		i, err := checkIndex(index, subject, len(runes))
+		if err != nil {
+			return err
+		}
+		return string(runes[i])
+	case map[any]any:
+		if !isHashable(index) {
+			return NewRuntimeError("cannot use %s as a map key", typeName(index))
+		}
+		return subject[index]
+	default:
+		return NewRuntimeError("cannot index %s", typeName(subject))
+	}
+}
+
+// checkIndex checks an index into an array or a string of the given length, counting a
+// negative index back from the end, so that -1 is the last item.
+func checkIndex(index any, subject any, length int) (int64, error) {
+	i, ok := index.(int64)
+	if !ok {
+		return 0, NewRuntimeError("%s index must be an int, got %s", typeName(subject), typeName(index))
+	}
+	if i < 0 {
+		i += int64(length)
+	}
+	if i < 0 || i >= int64(length) {
+		return 0, NewRuntimeError("index %d out of range for %s of length %d", index, typeName(subject), length)
+	}
+	return i, nil
+}
+
+// evalSlice returns the part of a string, counted in characters, or a new array with the
+// part of an array.
+func (e *Evaluator) evalSlice(in Slice, scope *Scope) any {
+	var runes []rune
+	var items []any
+	switch subject := e.evalExpression(in.Subject, scope).(type) {
+	case string:
+		runes = []rune(subject)
+	case []any:
+		items = subject
+	case error:
+		return subject
+	default:
+		return NewRuntimeError("cannot slice %s", typeName(subject))
+	}
+	length := int64(len(items) + len(runes))
+	start, end := int64(0), length
+	for _, bound := range []struct {
+		expression Expression
+		value      *int64
+	}{{in.Start, &start}, {in.End, &end}} {
+		if bound.expression == nil {
+			continue
+		}
+		switch value := e.evalExpression(bound.expression, scope).(type) {
+		case int64:
+			*bound.value = value
+		case error:
+			return value
+		default:
+			return NewRuntimeError("slice bounds must be ints, got %s", typeName(value))
+		}
+	}
+	if start < 0 || end > length || start > end {
+		return NewRuntimeError("slice bounds [%d:%d] out of range for length %d", start, end, length)
+	}
+	if runes != nil {
+		return string(runes[start:end])
+	}
+	return append(newArray(0, int(end-start)), items[start:end]...)
+}
+
+func (e *Evaluator) evalCall(in Call, scope *Scope) any {
+	var callee any
+	if identifier, ok := in.Function.(Identifier); ok {
+		var found bool
+		if callee, found = scope.Lookup(identifier); !found {
+			if builtin, ok := getBuiltin(identifier); ok {
+				return e.evalBuiltin(builtin, in, scope)
+			}
+			return NewRuntimeError("undefined function %s", identifier.Token.Value)
+		}
+	} else {
+		callee = e.evalExpression(in.Function, scope)
+	}
+	if err, ok := callee.(error); ok {
+		return err
+	}
+	if !isCallable(callee) {
+		return NewRuntimeError("cannot call %s", typeName(callee))
+	}
+	arguments, err := e.evalItems(in.Arguments, scope)
+	if err != nil {
+		return err
+	}
+	return e.callFunction(callee, arguments, scope)
+}
+
+// callFunction calls a function value with arguments that are already evaluated.
+func (e *Evaluator) callFunction(callee any, arguments []any, scope *Scope) any {
+	switch function := callee.(type) {
+	case Function:
+		return e.callUserFunction(function, arguments, scope)
+	case Partial:
+		bound := append(append([]any{}, function.Arguments...), arguments...)
+		return e.callFunction(function.Function, bound, scope)
+	case Composition:
+		last := len(function.Functions) - 1
+		result := e.callFunction(function.Functions[last], arguments, scope)
+		for i := last - 1; i >= 0; i-- {
+			result = e.callFunction(function.Functions[i], []any{result}, scope)
+		}
+		return result
+	case Native:
+		result, err := function.Function(arguments)
+		if err != nil {
+			return NewRuntimeError("%s: %s", function.Name, err)
+		}
+		return FromGo(result)
+	case Memo:
+		key, err := memoKey(arguments)
+		if err != nil {
+			return err
+		}
+		if result, ok := function.cache[key]; ok {
+			return result
+		}
+		result := e.callFunction(function.Function, arguments, scope)
+		if _, failed := result.(error); !failed {
+			function.cache[key] = result
+		}
+		return result
+	default:
+		return NewRuntimeError("cannot call %s", typeName(callee))
+	}
+}
+
+// arityError reports a call to a user function with the wrong number of arguments.
+func arityError(function Function, got int) error {
+	name := function.Name.Token.Value
+	if name == "" {
+		name = "function"
+	}
+	want := fmt.Sprintf("%d arguments", len(function.Parameters))
+	if len(function.Parameters) == 1 {
+		want = "1 argument"
+	}
+	return NewRuntimeError("%s expects %s, got %d", name, want, got)
+}
+
+// deferred is an expression a defer statement scheduled, with the scope to evaluate it in.
+type deferred struct {
+	value Expression
+	scope *Scope
+}
+
+// callUserFunction runs the function and then its deferred expressions, last deferred
+// first. They run after the result is known, so they can't change it, but an error in
+// one of them is returned in its place.
+func (e *Evaluator) callUserFunction(function Function, arguments []any, scope *Scope) any {
+	if e.maxDepth > 0 && e.depth >= e.maxDepth {
+		return NewRuntimeError("maximum recursion depth exceeded")
+	}
+	e.depth++
+	defer func() { e.depth-- }()
+	caller, callerDeferred := e.function, e.deferred
+	e.function, e.deferred = &function, nil
+	defer func() { e.function, e.deferred = caller, callerDeferred }()
+	result := e.runUserFunction(function, arguments, scope)
+	for i := len(e.deferred) - 1; i >= 0; i-- {
+		value := e.evalExpression(e.deferred[i].value, e.deferred[i].scope)
+		if err, ok := value.(error); ok {
+			if _, failed := result.(error); !failed {
+				result = err
+			}
+		}
+	}
+	return result
+}
+
+func (e *Evaluator) runUserFunction(function Function, arguments []any, scope *Scope) any {
+	var carried *Scope
+	for {
+		if len(function.Parameters) != len(arguments) {
+			return arityError(function, len(arguments))
+		}
+		if err := e.ctx.Err(); err != nil {
+			return err
+		}
+		parent := scope
+		if function.scope != nil {
+			parent = function.scope
+		}
+		newScope := NewScope(parent)
+		for i, argument := range arguments {
+			newScope.SetVariable(function.Parameters[i], argument)
+		}
+		switch result := e.evalBlock(function.Body, newScope).(type) {
+		case tailCall:
+			arguments = result.arguments
+			if function.scope == nil {
+				// a declared function sees the scope it's called from, which for the next
+				// call is the one just run. Its names are carried over in one scope, rather
+				// than a chain of scopes as long as the recursion.
+				if carried == nil {
+					carried = NewScope(scope)
+				}
+				for name, value := range newScope.variables {
+					carried.variables[name] = value
+				}
+				for name, value := range newScope.functions {
+					carried.functions[name] = value
+				}
+				scope = carried
+			}
+		case ReturnValue:
+			return result.Value
+		default:
+			return result
+		}
+	}
+}
+
+// Partial is the function value returned by partial. Calling it calls Function with
+// Arguments followed by the arguments of the call.
+type Partial struct {
+	Function  any
+	Arguments []any
+}
+
+// Composition is the function value returned by compose. Calling it calls the last of
+// Functions with the arguments of the call, then each function before it with the result.
+type Composition struct {
+	Functions []any
+}
+
+// Memo is the function value returned by memo. It remembers the result of Function for
+// every list of arguments it has been called with.
+type Memo struct {
+	Function any
+	cache    map[string]any
+}
+
+// Native is a function written in Go that the host registered with Scope.RegisterNative.
+type Native struct {
+	Name     string
+	Function func(args []any) (any, error)
+}
+
+// memoKey identifies a list of arguments. Only scalars can be part of a key, because the
+// items of an array or a map can change after the call.
+func memoKey(arguments []any) (string, error) {
+	parts := make([]string, len(arguments))
+	for i, argument := range arguments {
+		switch argument.(type) {
+		case nil, bool, int64, float64, string:
+			// quoting strings keeps a comma in one from reading as the start of another
+			parts[i] = typeName(argument) + ":" + InspectSource(argument, 0)
+		default:
+			return "", NewRuntimeError("memo cannot remember calls with %s arguments", typeName(argument))
+		}
+	}
+	return strings.Join(parts, ","), nil
+}
+
+func isCallable(value any) bool {
+	switch value.(type) {
+	case Function, Partial, Composition, Memo, Native:
+		return true
+	default:
+		return false
+	}
+}
+
+// evalItems evaluates the items of an array literal or the arguments of a call, splicing
+// in the items of the spread arrays.
+func (e *Evaluator) evalItems(in []Expression, scope *Scope) ([]any, error) {
+	items := newArray(0, len(in))
+	for _, item := range in {
+		spread, ok := item.(Spread)
+		if !ok {
+			value := e.evalExpression(item, scope)
+			if err, failed := value.(error); failed {
+				return nil, err
+			}
+			items = append(items, value)
+			continue
+		}
+		switch value := e.evalExpression(spread.Value, scope).(type) {
+		case []any:
+			items = append(items, value...)
+		case error:
+			return nil, value
+		default:
+			return nil, locate(NewRuntimeError("cannot spread %s, only an array", typeName(value)), spread).(error)
+		}
+	}
+	return items, nil
+}
+
+func (e *Evaluator) evalIdentifier(identifier Identifier, scope *Scope) any {
+	if identifier.Token.Value == "_" {
+		return NewRuntimeError("cannot read from _")
+	}
+	value, ok := scope.Lookup(identifier)
+	if !ok {
+		return NewRuntimeError("undefined variable %s", identifier.Token.Value)
+	}
+	return value
+}
+
+func (e *Evaluator) evalUnaryOperation(in UnaryOperation, scope *Scope) any {
+	operand := e.evalExpression(in.Expression, scope)
+	switch t := operand.(type) {
+	case bool:
+		if in.Token.Type == NOT {
+			return !t
+		}
+	case int64:
+		if in.Token.Type == PLUS {
+			return t
+		}
+		if in.Token.Type == MINUS {
+			return -1 * t
+		}
+	case float64:
+		if in.Token.Type == PLUS {
+			return t
+		}
+		if in.Token.Type == MINUS {
+			return -1 * t
+		}
+	case error:
+		return t
+	}
+	return NewRuntimeError("cannot apply %s to %s", in.Token.Value, typeName(operand))
+}
+
+// evalBinaryOperation evaluates both operands and applies the operator to them. The
+// operations for each pair of types return nil when the operator doesn't apply to them,
+// which is reported here as an error naming both types.
+func (e *Evaluator) evalBinaryOperation(in BinaryOperation, scope *Scope) any {
+	left := e.evalExpression(in.Left, scope)
+	right := e.evalExpression(in.Right, scope)
+	for _, operand := range []any{left, right} {
+		if err, ok := operand.(error); ok {
+			return err
+		}
+	}
+	var result any
+	switch left := left.(type) {
+	case bool:
+		if right, ok := right.(bool); ok {
+			result = evalBinaryOperationBoolBool(left, right, in.Token)
+		}
+	case int64:
+		switch right := right.(type) {
+		case int64:
+			result = evalBinaryOperationIntInt(left, right, in.Token)
+		case float64:
+			result = evalBinaryOperationIntFloat(left, right, in.Token)
+		}
+	case float64:
+		switch right := right.(type) {
+		case int64:
+			result = evalBinaryOperationFloatInt(left, right, in.Token)
+		case float64:
+			result = evalBinaryOperationFloatFloat(left, right, in.Token)
+		}
+	case string:
+		if right, ok := right.(string); ok {
+			result = evalBinaryOperationStringString(left, right, in.Token)
+		}
+	case Function:
+		if right, ok := right.(Function); ok {
+			result = evalBinaryOperationFunctionFunction(left, right, in.Token)
+		}
+	case []any, map[any]any:
+		if typeName(left) == typeName(right) {
+			result = evalBinaryOperationContainers(left, right, in.Token)
+		}
+	}
+	if result == nil {
+		return NewRuntimeError("cannot %s %s and %s", operationName(in.Token), typeName(left), typeName(right))
+	}
+	return result
+}
+
+// operationName names what the operator does, for errors like "cannot add bool and int".
+func operationName(operator Token) string {
+	switch operator.Type {
+	case PLUS:
+		return "add"
+	case MINUS:
+		return "subtract"
+	case ASTERISK:
+		return "multiply"
+	case SLASH:
+		return "divide"
+	case LT, GT, LEQ, GEQ, EQ, NEQ:
+		return "compare"
+	default:
+		return "apply " + operator.Value + " to"
+	}
+}
+
+func evalBinaryOperationBoolBool(left bool, right bool, operator Token) any {
+	switch operator.Type {
+	case EQ:
+		return left == right
+	case NEQ:
+		return left != right
+	case OR:
+		return left || right
+	case AND:
+		return left && right
+	default:
+		return nil
+	}
+}
+
+func evalBinaryOperationIntInt(left int64, right int64, operator Token) any {
+	switch operator.Type {
+	case LT:
+		return left < right
+	case GT:
+		return left > right
+	case LEQ:
+		return left <= right
+	case GEQ:
+		return left >= right
+	case EQ:
+		return left == right
+	case NEQ:
+		return left != right
+	case PLUS:
+		return left + right
+	case MINUS:
+		return left - right
+	case ASTERISK:
+		return left * right
+	case SLASH:
+		if right == 0 {
+			return NewRuntimeError("division by zero")
+		}
+		return left / right
+	case PERCENT:
+		if right == 0 {
+			return NewRuntimeError("modulo by zero")
+		}
+		return left % right
+	case AMPERSAND:
+		return left & right
+	case PIPE:
+		return left | right
+	case CARET:
+		return left ^ right
+	case LSHIFT, RSHIFT:
+		if right < 0 {
+			return NewRuntimeError("negative shift count %d", right)
+		}
+		if operator.Type == LSHIFT {
+			return left << right
+		}
+		return left >> right
+	default:
+		return nil
+	}
+}
+
+func evalBinaryOperationIntFloat(left int64, right float64, operator Token) any {
+	switch operator.Type {
+	case LT:
+		return float64(left) < right
+	case GT:
+		return float64(left) > right
+	case LEQ:
+		return float64(left) <= right
+	case GEQ:
+		return float64(left) >= right
+	case EQ:
+		return float64(left) == right
+	case NEQ:
+		return float64(left) != right
+	case PLUS:
+		return float64(left) + right
+	case MINUS:
+		return float64(left) - right
+	case ASTERISK:
+		return float64(left) * right
+	case SLASH:
+		return float64(left) / right
+	case PERCENT:
+		return math.Mod(float64(left), right)
+	default:
+		return nil
+	}
+}
+
+func evalBinaryOperationFloatInt(left float64, right int64, operator Token) any {
+	switch operator.Type {
+	case LT:
+		return left < float64(right)
+	case GT:
+		return left > float64(right)
+	case LEQ:
+		return left <= float64(right)
+	case GEQ:
+		return left >= float64(right)
+	case EQ:
+		return left == float64(right)
+	case NEQ:
+		return left != float64(right)
+	case PLUS:
+		return left + float64(right)
+	case MINUS:
+		return left - float64(right)
+	case ASTERISK:
+		return left * float64(right)
+	case SLASH:
+		return left / float64(right)
+	case PERCENT:
+		return math.Mod(left, float64(right))
+	default:
+		return nil
+	}
+}
+
+func evalBinaryOperationFloatFloat(left float64, right float64, operator Token) any {
+	switch operator.Type {
+	case LT:
+		return left < right
+	case GT:
+		return left > right
+	case LEQ:
+		return left <= right
+	case GEQ:
+		return left >= right
+	case EQ:
+		return left == right
+	case NEQ:
+		return left != right
+	case PLUS:
+		return left + right
+	case MINUS:
+		return left - right
+	case ASTERISK:
+		return left * right
+	case SLASH:
+		return left / right
+	case PERCENT:
+		return math.Mod(left, right)
+	default:
+		return nil
+	}
+}
+
+func evalBinaryOperationStringString(left string, right string, operator Token) any {
+	switch operator.Type {
+	case LT:
+		return left < right
+	case GT:
+		return left > right
+	case LEQ:
+		return left <= right
+	case GEQ:
+		return left >= right
+	case EQ:
+		return left == right
+	case NEQ:
+		return left != right
+	case PLUS:
+		return left + right
+	default:
+		return nil
+	}
+}
+
+// Arrays and maps are equal when their items are.
+func evalBinaryOperationContainers(left any, right any, operator Token) any {
+	switch operator.Type {
+	case EQ:
+		return deepEqual(left, right)
+	case NEQ:
+		return !deepEqual(left, right)
+	default:
+		return nil
+	}
+}
+
+// Functions are equal when they come from the same declaration, not when they merely have
+// the same source.
+func evalBinaryOperationFunctionFunction(left Function, right Function, operator Token) any {
+	switch operator.Type {
+	case EQ:
+		return sameFunction(left, right)
+	case NEQ:
+		return !sameFunction(left, right)
+	default:
+		return nil
+	}
+}
+
+func sameFunction(left Function, right Function) bool {
+	return left.Name == right.Name &&
+		left.scope == right.scope &&
+		sameSlice(left.Parameters, right.Parameters) &&
+		sameSlice(left.Body.Statements, right.Body.Statements)
+}
+
+// sameSlice reports whether two slices share their backing array, rather than whether
+// their items are equal.
+func sameSlice[T any](left []T, right []T) bool {
+	return len(left) == len(right) && (len(left) == 0 || &left[0] == &right[0])
+}
+
+// evalRange makes an array of the ints in the range, for a range used outside a for loop.
+func (e *Evaluator) evalRange(in Range, scope *Scope) any {
+	start, end, err := e.evalBounds(in, scope)
+	if err != nil {
+		return err
+	}
+	size := end - start
+	if size < 0 {
+		size = 0
+	}
+	items := newArray(0, int(size))
+	for i := start; i < end; i++ {
+		items = append(items, i)
+	}
+	return items
+}
+
+func (e *Evaluator) evalBounds(in Range, scope *Scope) (int64, int64, error) {
+	start := e.evalExpression(in.Start, scope)
+	end := e.evalExpression(in.End, scope)
+	for _, bound := range []any{start, end} {
+		if err, ok := bound.(error); ok {
+			return 0, 0, err
+		}
+	}
+	startInt, startOk := start.(int64)
+	endInt, endOk := end.(int64)
+	if !startOk || !endOk {
+		return 0, 0, NewRuntimeError("range bounds must be ints, got %s and %s", typeName(start), typeName(end))
+	}
+	return startInt, endInt, nil
+}
+
+func (e *Evaluator) evalLen(in Len, scope *Scope) any {
+	switch typedSubject := e.evalExpression(in.Subject, scope).(type) {
+	case string:
+		return int64(utf8.RuneCountInString(typedSubject))
+	case []any:
+		return int64(len(typedSubject))
+	case map[any]any:
+		return int64(len(typedSubject))
+	case error:
+		return typedSubject
+	default:
+		return NewRuntimeError("len expects a string, an array, or a map, got %s", typeName(typedSubject))
+	}
+}
+
+func (e *Evaluator) evalPrint(in Print, scope *Scope) any {
+	values, err := e.evalItems(in.Args, scope)
+	if err != nil {
+		return err
+	}
+	args := make([]string, len(values))
+	for i, value := range values {
+		args[i] = InspectPrecision(value, e.precision)
+	}
+	end := ""
+	if in.IsNewLine {
+		end = "\n"
+	}
+	separator, err := e.evalPrintOption("sep", in.Separator, " ", scope)
+	if err != nil {
+		return err
+	}
+	end, err = e.evalPrintOption("end", in.End, end, scope)
+	if err != nil {
+		return err
+	}
+	rendered := strings.Join(args, separator) + end
+	if in.IsString {
+		return rendered
+	}
+	fmt.Fprint(e.out, rendered)
+	return nil
+}
+
+// evalPrintOption evaluates the sep or end argument of a print, which must be a string,
+// or returns fallback when it wasn't given.
+func (e *Evaluator) evalPrintOption(name string, option Expression, fallback string, scope *Scope) (string, error) {
+	if option == nil {
+		return fallback, nil
+	}
+	switch value := e.evalExpression(option, scope).(type) {
+	case string:
+		return value, nil
+	case error:
+		return "", value
+	default:
+		return "", locate(NewRuntimeError("%s must be a string, got %s", name, typeName(value)), option).(error)
+	}
+}
+
+// ************
+// ** Values **
+// ************
+
+// Inspect renders a value the way print shows it. Numbers are rendered the same way on
+// every platform, and floats only use scientific notation when they are too large or too
+// small to be read otherwise. Arrays and maps are rendered as Uni source, with the strings
+// inside them quoted and the map keys sorted.
+func Inspect(value any) string {
+	return InspectPrecision(value, 0)
+}
+
+// InspectPrecision is Inspect with floats rounded to the given number of significant
+// digits for display. A precision of 0 shows floats in full.
+func InspectPrecision(value any, precision int) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	return inspector{visiting: map[uintptr]bool{}, precision: precision}.inspect(value)
+}
+
+// InspectSource is InspectPrecision with strings quoted, even at the top level, so the
+// value reads the way it would be written.
+func InspectSource(value any, precision int) string {
+	return inspector{visiting: map[uintptr]bool{}, precision: precision}.inspect(value)
+}
+
+// inspector renders values nested in containers. visiting holds the containers being
+// rendered, so a container that holds itself is rendered as a placeholder instead of
+// recursing forever.
+type inspector struct {
+	visiting  map[uintptr]bool
+	precision int
+}
+
+func (r inspector) inspect(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "nil"
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return r.inspectFloat(v)
+	case string:
+		return strconv.Quote(v)
+	case []any:
+		pointer := reflect.ValueOf(v).Pointer()
+		if len(v) > 0 && r.visiting[pointer] {
+			return "[...]"
+		}
+		r.visiting[pointer] = true
+		defer delete(r.visiting, pointer)
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = r.inspect(item)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case map[any]any:
+		pointer := reflect.ValueOf(v).Pointer()
+		if r.visiting[pointer] {
+			return "{...}"
+		}
+		r.visiting[pointer] = true
+		defer delete(r.visiting, pointer)
+		keys := make([]any, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.SliceStable(keys, func(i, j int) bool {
+			if result, ok := compareValues(keys[i], keys[j]); ok {
+				return result < 0
+			}
+			if keyRank(keys[i]) != keyRank(keys[j]) {
+				return keyRank(keys[i]) < keyRank(keys[j])
+			}
+			return r.inspect(keys[i]) < r.inspect(keys[j])
+		})
+		items := make([]string, len(keys))
+		for i, key := range keys {
+			items[i] = r.inspect(key) + ": " + r.inspect(v[key])
+		}
+		return "{" + strings.Join(items, ", ") + "}"
+	case Function:
+		names := make([]string, len(v.Parameters))
+		for i, parameter := range v.Parameters {
+			names[i] = parameter.Token.Value
+		}
+		return strings.TrimSpace("fn "+v.Name.Token.Value) + "(" + strings.Join(names, ", ") + ")"
+	case Partial:
+		arguments := []string{r.inspect(v.Function)}
+		for _, argument := range v.Arguments {
+			arguments = append(arguments, r.inspect(argument))
+		}
+		return "partial(" + strings.Join(arguments, ", ") + ")"
+	case Composition:
+		functions := make([]string, len(v.Functions))
+		for i, function := range v.Functions {
+			functions[i] = r.inspect(function)
+		}
+		return "compose(" + strings.Join(functions, ", ") + ")"
+	case Memo:
+		return "memo(" + r.inspect(v.Function) + ")"
+	case Native:
+		return "native " + v.Name
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// ToGo converts a value of a Uni program into the Go value a host would expect: maps
+// whose keys are all strings become map[string]any, and the items of arrays and maps are
+// converted as well.
+func ToGo(value any) any {
+	switch v := value.(type) {
+	case []any:
+		items := make([]any, len(v))
+		for i, item := range v {
+			items[i] = ToGo(item)
+		}
+		return items
+	case map[any]any:
+		stringKeys := make(map[string]any, len(v))
+		for key, item := range v {
+			s, ok := key.(string)
+			if !ok {
+				stringKeys = nil
+				break
+			}
+			stringKeys[s] = ToGo(item)
+		}
+		if stringKeys != nil {
+			return stringKeys
+		}
+		items := make(map[any]any, len(v))
+		for key, item := range v {
+			items[key] = ToGo(item)
+		}
+		return items
+	default:
+		return value
+	}
+}
+
+// FromGo converts a Go value into the representation a Uni program works with: every
+// integer becomes an int64, every float a float64, every slice or array a []any, and every
+// map a map[any]any. Other values are returned as they are.
+func FromGo(value any) any {
+	if value == nil {
+		return nil
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Bool:
+		return v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return int64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	case reflect.String:
+		return v.String()
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return newArray(0, 0)
+		}
+		items := newArray(v.Len(), v.Len())
+		for i := range items {
+			items[i] = FromGo(v.Index(i).Interface())
+		}
+		return items
+	case reflect.Map:
+		items := make(map[any]any, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			items[FromGo(iter.Key().Interface())] = FromGo(iter.Value().Interface())
+		}
+		return items
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return FromGo(v.Elem().Interface())
+	default:
+		return value
+	}
+}
+
+// keyRank orders map keys that can't be compared: numbers first, then strings, then the rest.
+func keyRank(key any) int {
+	switch key.(type) {
+	case int64, float64:
+		return 0
+	case string:
+		return 1
+	default:
+		return 2
+	}
+}
+
+func (r inspector) inspectFloat(value float64) string {
+	if r.precision > 0 {
+		value, _ = strconv.ParseFloat(strconv.FormatFloat(value, 'g', r.precision, 64), 64)
+	}
+	if abs := math.Abs(value); abs != 0 && (abs < 1e-6 || abs >= 1e21) {
+		return strconv.FormatFloat(value, 'e', -1, 64)
+	}
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// **************
+// ** Builtins **
+// **************
+
+type Builtin func(e *Evaluator, args []any) any
+
+// BuiltinNames lists the names of the builtin functions, in no particular order.
+func BuiltinNames() []string {
+	names := make([]string, 0, len(builtins))
+	for name := range builtins {
+		names = append(names, name)
+	}
+	return names
+}
+
+// builtins is filled in by init, since some of the builtins call functions, which look
+// the builtins up in turn.
+var builtins map[string]Builtin
+
+func init() {
+	builtins = map[string]Builtin{
+		"sorted":        builtinSorted,
+		"reversed":      builtinReversed,
+		"assert":        builtinAssert,
+		"assert_eq":     builtinAssertEq,
+		"clamp":         builtinClamp,
+		"sign":          builtinSign,
+		"trunc":         builtinTrunc,
+		"abs":           builtinAbs,
+		"min":           builtinMin,
+		"max":           builtinMax,
+		"sqrt":          builtinSqrt,
+		"floor":         builtinRounding("floor", math.Floor),
+		"ceil":          builtinRounding("ceil", math.Ceil),
+		"round":         builtinRounding("round", math.Round),
+		"popcount":      builtinPopcount,
+		"bit_length":    builtinBitLength,
+		"gcd":           builtinGcd,
+		"lcm":           builtinLcm,
+		"str":           builtinStr,
+		"partial":       builtinPartial,
+		"compose":       builtinCompose,
+		"memo":          builtinMemo,
+		"version":       builtinVersion,
+		"deep_equal":    builtinDeepEqual,
+		"freeze":        builtinFreeze,
+		"is_frozen":     builtinIsFrozen,
+		"delete":        builtinDelete,
+		"keys":          builtinKeys,
+		"values":        builtinValues,
+		"push":          builtinPush,
+		"trim_prefix":   builtinTrimPrefix,
+		"trim_suffix":   builtinTrimSuffix,
+		"upper":         builtinUpper,
+		"lower":         builtinLower,
+		"trim":          builtinTrim,
+		"replace":       builtinReplace,
+		"contains":      builtinContains,
+		"split":         builtinSplit,
+		"join":          builtinJoin,
+		"regex_match":   builtinRegexMatch,
+		"regex_find":    builtinRegexFind,
+		"regex_replace": builtinRegexReplace,
+		"base64_encode": builtinBase64Encode,
+		"base64_decode": builtinBase64Decode,
+		"hex_encode":    builtinHexEncode,
+		"hex_decode":    builtinHexDecode,
+		"md5":           builtinHash("md5", md5.New),
+		"sha1":          builtinHash("sha1", sha1.New),
+		"sha256":        builtinHash("sha256", sha256.New),
+		"http_get":      builtinHTTPGet,
+		"read_file":     builtinReadFile,
+		"write_file":    builtinWriteFile,
+		"env":           builtinEnv,
+		"input":         builtinInput,
+		"type":          builtinType,
+	}
+}
+
+// unsafeBuiltins lists the builtins that are disabled in the sandbox.
+var unsafeBuiltins = map[string]bool{
+	"http_get":   true,
+	"read_file":  true,
+	"write_file": true,
+	"env":        true,
+}
+
+func getBuiltin(identifier Identifier) (Builtin, bool) {
+	builtin, ok := builtins[identifier.Token.Value]
+	return builtin, ok
+}
+
+func (e *Evaluator) evalBuiltin(builtin Builtin, in Call, scope *Scope) any {
+	if name := in.Function.(Identifier).Token.Value; e.sandbox && unsafeBuiltins[name] {
+		return NewRuntimeError("%s is disabled in the sandbox", name)
+	}
+	arguments, err := e.evalItems(in.Arguments, scope)
+	if err != nil {
+		return err
+	}
+	return builtin(e, arguments)
+}
+
+// builtinSorted returns the keys of a map, or a copy of an array, in ascending order.
+// Go randomizes map iteration, so this is the way to get a reproducible for loop.
+func builtinSorted(_ *Evaluator, args []any) any {
+	if len(args) != 1 {
+		return NewRuntimeError("sorted expects 1 argument, got %d", len(args))
+	}
+	var items []any
+	switch subject := args[0].(type) {
+	case []any:
+		items = append(items, subject...)
+	case map[any]any:
+		for key := range subject {
+			items = append(items, key)
+		}
+	default:
+		return NewRuntimeError("sorted expects an array or a map, got %s", typeName(args[0]))
+	}
+	var err error
+	sort.SliceStable(items, func(i, j int) bool {
+		result, ok := compareValues(items[i], items[j])
+		if !ok && err == nil {
+			err = NewRuntimeError("cannot compare %s and %s", typeName(items[i]), typeName(items[j]))
+		}
+		return result < 0
+	})
+	if err != nil {
+		return err
+	}
+	if items == nil {
+		items = newArray(0, 0)
+	}
+	return items
+}
+
+// builtinReversed returns a copy of an array, or a string, in reverse order, so a for
+// loop can walk it backward.
+func builtinReversed(_ *Evaluator, args []any) any {
+	if len(args) != 1 {
+		return NewRuntimeError("reversed expects 1 argument, got %d", len(args))
+	}
+	switch subject := args[0].(type) {
+	case []any:
+		items := newArray(len(subject), len(subject))
+		for i, item := range subject {
+			items[len(subject)-1-i] = item
+		}
+		return items
+	case string:
+		runes := []rune(subject)
+		for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+			runes[i], runes[j] = runes[j], runes[i]
+		}
+		return string(runes)
+	}
+	return NewRuntimeError("reversed expects an array or a string, got %s", typeName(args[0]))
+}
+
+// builtinAssert fails when its first argument isn't true. An optional second argument
+// is added to the failure message.
+func builtinAssert(e *Evaluator, args []any) any {
+	if len(args) != 1 && len(args) != 2 {
+		return NewRuntimeError("assert expects 1 or 2 arguments, got %d", len(args))
+	}
+	if args[0] == true {
+		return e.passAssertion()
+	}
+	message := "assertion failed"
+	if len(args) == 2 {
+		message += ": " + fmt.Sprint(args[1])
+	}
+	return e.failAssertion(message)
+}
+
+// builtinAssertEq fails when its two arguments aren't equal. Numbers are compared by
+// value, so 1 and 1.0 are equal.
+func builtinAssertEq(e *Evaluator, args []any) any {
+	if len(args) != 2 {
+		return NewRuntimeError("assert_eq expects 2 arguments, got %d", len(args))
+	}
+	if deepEqual(args[0], args[1]) {
+		return e.passAssertion()
+	}
+	return e.failAssertion(fmt.Sprintf("assertion failed: %s != %s", Inspect(args[0]), Inspect(args[1])))
+}
+
+// builtinDeepEqual is ==, except that values of different types are not equal rather
+// than an error.
+func builtinDeepEqual(_ *Evaluator, args []any) any {
+	if len(args) != 2 {
+		return NewRuntimeError("deep_equal expects 2 arguments, got %d", len(args))
+	}
+	return deepEqual(args[0], args[1])
+}
+
+// builtinFreeze makes an array or a map, and everything inside it, immutable. It returns
+// the value, so it can wrap a literal.
+func builtinFreeze(e *Evaluator, args []any) any {
+	if len(args) != 1 {
+		return NewRuntimeError("freeze expects 1 argument, got %d", len(args))
+	}
+	switch args[0].(type) {
+	case []any, map[any]any:
+	default:
+		return NewRuntimeError("freeze expects an array or a map, got %s", typeName(args[0]))
+	}
+	e.root.Freeze(args[0])
+	return args[0]
+}
+
+// builtinDelete removes a key from a map, in place. Deleting a key that isn't there does
+// nothing.
+func builtinDelete(e *Evaluator, args []any) any {
+	if len(args) != 2 {
+		return NewRuntimeError("delete expects 2 arguments, got %d", len(args))
+	}
+	m, ok := args[0].(map[any]any)
+	if !ok {
+		return NewRuntimeError("delete expects a map, got %s", typeName(args[0]))
+	}
+	if e.root.IsFrozen(m) {
+		return NewRuntimeError("cannot change a frozen map")
+	}
+	if isHashable(args[1]) {
+		delete(m, args[1])
+	}
+	return nil
+}
+
+// builtinKeys returns the keys of a map in an array. Like a for loop over the map, it
+// visits them in no particular order; sorted returns them in a reproducible one.
+func builtinKeys(_ *Evaluator, args []any) any {
+	m, err := mapArgument("keys", args)
+	if err != nil {
+		return err
+	}
+	keys := newArray(0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// builtinValues returns the values of a map in an array, in no particular order.
+func builtinValues(_ *Evaluator, args []any) any {
+	m, err := mapArgument("values", args)
+	if err != nil {
+		return err
+	}
+	values := newArray(0, len(m))
+	for _, value := range m {
+		values = append(values, value)
+	}
+	return values
+}
+
+// mapArgument checks that the builtin got exactly one map.
+func mapArgument(name string, args []any) (map[any]any, error) {
+	if len(args) != 1 {
+		return nil, NewRuntimeError("%s expects 1 argument, got %d", name, len(args))
+	}
+	m, ok := args[0].(map[any]any)
+	if !ok {
+		return nil, NewRuntimeError("%s expects a map, got %s", name, typeName(args[0]))
+	}
+	return m, nil
+}
+
+// builtinPush returns a new array with the values added to the end. The array itself is
+// left alone, so other variables holding it don't see the values appear.
+func builtinPush(_ *Evaluator, args []any) any {
+	if len(args) < 2 {
+		return NewRuntimeError("push expects an array and at least 1 value, got %d arguments", len(args))
+	}
+	array, ok := args[0].([]any)
+	if !ok {
+		return NewRuntimeError("push expects an array, got %s", typeName(args[0]))
+	}
+	pushed := newArray(0, len(array)+len(args)-1)
+	pushed = append(pushed, array...)
+	return append(pushed, args[1:]...)
+}
+
+func builtinIsFrozen(e *Evaluator, args []any) any {
+	if len(args) != 1 {
+		return NewRuntimeError("is_frozen expects 1 argument, got %d", len(args))
+	}
+	return e.root.IsFrozen(args[0])
+}
+
+// builtinType returns the name of the value's type, the same name typematch uses.
+func builtinType(_ *Evaluator, args []any) any {
+	if len(args) != 1 {
+		return NewRuntimeError("type expects 1 argument, got %d", len(args))
+	}
+	return typeName(args[0])
+}
+
+// builtinStr renders any value as a string, the same way print does.
+func builtinStr(e *Evaluator, args []any) any {
+	if len(args) != 1 {
+		return NewRuntimeError("str expects 1 argument, got %d", len(args))
+	}
+	return InspectPrecision(args[0], e.precision)
+}
+
+// builtinPartial binds the first arguments of a function, returning a function that takes
+// the rest.
+func builtinPartial(_ *Evaluator, args []any) any {
+	if len(args) == 0 {
+		return NewRuntimeError("partial expects a function")
+	}
+	if !isCallable(args[0]) {
+		return NewRuntimeError("partial expects a function, got %s", typeName(args[0]))
+	}
+	return Partial{Function: args[0], Arguments: append([]any{}, args[1:]...)}
+}
+
+// builtinCompose combines two or more functions right to left, so compose(f, g)(x) is
+// f(g(x)).
+func builtinCompose(_ *Evaluator, args []any) any {
+	if len(args) < 2 {
+		return NewRuntimeError("compose expects at least 2 functions, got %d", len(args))
+	}
+	for _, arg := range args {
+		if !isCallable(arg) {
+			return NewRuntimeError("compose expects functions, got %s", typeName(arg))
+		}
+	}
+	return Composition{Functions: append([]any{}, args...)}
+}
+
+// builtinMemo wraps a function so it's only called once for the same arguments, the later
+// calls returning the remembered result.
+func builtinMemo(_ *Evaluator, args []any) any {
+	if len(args) != 1 {
+		return NewRuntimeError("memo expects 1 argument, got %d", len(args))
+	}
+	if !isCallable(args[0]) {
+		return NewRuntimeError("memo expects a function, got %s", typeName(args[0]))
+	}
+	return Memo{Function: args[0], cache: map[string]any{}}
+}
+
+func builtinVersion(_ *Evaluator, args []any) any {
+	if len(args) != 0 {
+		return NewRuntimeError("version expects no arguments, got %d", len(args))
+	}
+	return Version
+}
+
+// builtinClamp bounds a number to the [lo, hi] range. The result is an int only when all
+// three arguments are ints.
+func builtinClamp(_ *Evaluator, args []any) any {
+	if len(args) != 3 {
+		return NewRuntimeError("clamp expects 3 arguments, got %d", len(args))
+	}
+	allInts := true
+	for _, arg := range args {
+		switch arg.(type) {
+		case int64:
+		case float64:
+			allInts = false
+		default:
+			return NewRuntimeError("clamp expects numbers, got %s", typeName(arg))
+		}
+	}
+	value, lo, hi := args[0], args[1], args[2]
+	if result, _ := compareValues(lo, hi); result > 0 {
+		return NewRuntimeError("clamp lower bound %s is greater than upper bound %s", Inspect(lo), Inspect(hi))
+	}
+	if result, _ := compareValues(value, lo); result < 0 {
+		value = lo
+	} else if result, _ := compareValues(value, hi); result > 0 {
+		value = hi
+	}
+	if allInts {
+		return value
+	}
+	return toFloat(value)
+}
+
+// builtinSign returns -1, 0, or 1 depending on the sign of the number.
+func builtinSign(_ *Evaluator, args []any) any {
+	if len(args) != 1 {
+		return NewRuntimeError("sign expects 1 argument, got %d", len(args))
+	}
+	switch args[0].(type) {
+	case int64, float64:
+	default:
+		return NewRuntimeError("sign expects a number, got %s", typeName(args[0]))
+	}
+	result, _ := compareValues(args[0], int64(0))
+	return int64(result)
+}
+
+// builtinTrunc drops the fractional part of a number, rounding toward zero.
+func builtinTrunc(_ *Evaluator, args []any) any {
+	if len(args) != 1 {
+		return NewRuntimeError("trunc expects 1 argument, got %d", len(args))
+	}
+	switch value := args[0].(type) {
+	case int64:
+		return value
+	case float64:
+		return floatToInt("trunc", math.Trunc(value))
+	default:
+		return NewRuntimeError("trunc expects a number, got %s", typeName(args[0]))
+	}
+}
+
+// floatToInt converts a float with no fractional part to an int, or reports that it's
+// infinite, NaN, or too large for an int.
+func floatToInt(name string, value float64) any {
+	// -2^63 is an int, but 2^63 is one past the largest
+	if !(value >= math.MinInt64 && value < -math.MinInt64) {
+		return NewRuntimeError("%s of %s is out of the range of an int", name, InspectPrecision(value, 0))
+	}
+	return int64(value)
+}
+
+// builtinAbs returns the absolute value of a number, which keeps its type.
+func builtinAbs(_ *Evaluator, args []any) any {
+	if len(args) != 1 {
+		return NewRuntimeError("abs expects 1 argument, got %d", len(args))
+	}
+	switch value := args[0].(type) {
+	case int64:
+		if value == math.MinInt64 {
+			return NewRuntimeError("abs of %d is out of the range of an int", value)
+		}
+		if value < 0 {
+			return -value
+		}
+		return value
+	case float64:
+		return math.Abs(value)
+	default:
+		return NewRuntimeError("abs expects a number, got %s", typeName(args[0]))
+	}
+}
+
+// builtinMin returns the smallest of its arguments, with the type it was passed with.
+func builtinMin(_ *Evaluator, args []any) any {
+	return extreme("min", args, -1)
+}
+
+// builtinMax returns the largest of its arguments, with the type it was passed with.
+func builtinMax(_ *Evaluator, args []any) any {
+	return extreme("max", args, 1)
+}
+
+// extreme returns the first of the numbers that compares as direction, -1 or 1, to the
+// ones before it.
+func extreme(name string, args []any, direction int) any {
+	if len(args) == 0 {
+		return NewRuntimeError("%s expects at least 1 argument, got 0", name)
+	}
+	var best any
+	for _, arg := range args {
+		switch arg.(type) {
+		case int64, float64:
+		default:
+			return NewRuntimeError("%s expects numbers, got %s", name, typeName(arg))
+		}
+		if best == nil {
+			best = arg
+		} else if result, _ := compareValues(arg, best); result == direction {
+			best = arg
+		}
+	}
+	return best
+}
+
+// builtinSqrt returns the square root of a number as a float.
+func builtinSqrt(_ *Evaluator, args []any) any {
+	if len(args) != 1 {
+		return NewRuntimeError("sqrt expects 1 argument, got %d", len(args))
+	}
+	switch args[0].(type) {
+	case int64, float64:
+	default:
+		return NewRuntimeError("sqrt expects a number, got %s", typeName(args[0]))
+	}
+	value := toFloat(args[0])
+	if value < 0 {
+		return NewRuntimeError("sqrt of negative number %s", Inspect(args[0]))
+	}
+	return math.Sqrt(value)
+}
+
+// builtinRounding makes a builtin that rounds a number to an int the way round does. Like
+// trunc, an int is returned as it is.
+func builtinRounding(name string, round func(float64) float64) Builtin {
+	return func(_ *Evaluator, args []any) any {
+		if len(args) != 1 {
+			return NewRuntimeError("%s expects 1 argument, got %d", name, len(args))
+		}
+		switch value := args[0].(type) {
+		case int64:
+			return value
+		case float64:
+			return floatToInt(name, round(value))
+		default:
+			return NewRuntimeError("%s expects a number, got %s", name, typeName(args[0]))
+		}
+	}
+}
+
+// builtinTrimPrefix removes a prefix from a string, which is returned unchanged when it
+// doesn't start with the prefix.
+func builtinTrimPrefix(_ *Evaluator, args []any) any {
+	s, err := stringArguments("trim_prefix", args, 2)
+	if err != nil {
+		return err
+	}
+	return strings.TrimPrefix(s[0], s[1])
+}
+
+// builtinTrimSuffix removes a suffix from a string, which is returned unchanged when it
+// doesn't end with the suffix.
+func builtinTrimSuffix(_ *Evaluator, args []any) any {
+	s, err := stringArguments("trim_suffix", args, 2)
+	if err != nil {
+		return err
+	}
+	return strings.TrimSuffix(s[0], s[1])
+}
+
+// builtinUpper returns the string with every letter in upper case.
+func builtinUpper(_ *Evaluator, args []any) any {
+	s, err := stringArguments("upper", args, 1)
+	if err != nil {
+		return err
+	}
+	return strings.ToUpper(s[0])
+}
+
+// builtinLower returns the string with every letter in lower case.
+func builtinLower(_ *Evaluator, args []any) any {
+	s, err := stringArguments("lower", args, 1)
+	if err != nil {
+		return err
+	}
+	return strings.ToLower(s[0])
+}
+
+// builtinTrim removes the whitespace at both ends of a string.
+func builtinTrim(_ *Evaluator, args []any) any {
+	s, err := stringArguments("trim", args, 1)
+	if err != nil {
+		return err
+	}
+	return strings.TrimSpace(s[0])
+}
+
+// builtinReplace replaces every occurrence of old in the string with new.
+func builtinReplace(_ *Evaluator, args []any) any {
+	s, err := stringArguments("replace", args, 3)
+	if err != nil {
+		return err
+	}
+	return strings.ReplaceAll(s[0], s[1], s[2])
+}
+
+// builtinContains reports whether the string contains the substring.
+func builtinContains(_ *Evaluator, args []any) any {
+	s, err := stringArguments("contains", args, 2)
+	if err != nil {
+		return err
+	}
+	return strings.Contains(s[0], s[1])
+}
+
+// builtinSplit returns the parts of a string between the separators. An empty separator
+// splits the string into its characters.
+func builtinSplit(_ *Evaluator, args []any) any {
+	s, err := stringArguments("split", args, 2)
+	if err != nil {
+		return err
+	}
+	parts := strings.Split(s[0], s[1])
+	items := make([]any, len(parts))
+	for i, part := range parts {
+		items[i] = part
+	}
+	return items
+}
+
+// builtinJoin returns the items of an array with the separator between them. Items that
+// aren't strings are written as print writes them.
+func builtinJoin(e *Evaluator, args []any) any {
+	if len(args) != 2 {
+		return NewRuntimeError("join expects 2 arguments, got %d", len(args))
+	}
+	items, ok := args[0].([]any)
+	if !ok {
+		return NewRuntimeError("join expects an array, got %s", typeName(args[0]))
+	}
+	separator, ok := args[1].(string)
+	if !ok {
+		return NewRuntimeError("join expects a string separator, got %s", typeName(args[1]))
+	}
+	parts := make([]string, len(items))
+	for i, item := range items {
+		parts[i] = InspectPrecision(item, e.precision)
+	}
+	return strings.Join(parts, separator)
+}
+
+// builtinRegexMatch reports whether the string contains a match of the pattern.
+func builtinRegexMatch(e *Evaluator, args []any) any {
+	s, err := stringArguments("regex_match", args, 2)
+	if err != nil {
+		return err
+	}
+	re, err := e.compileRegexp(s[0])
+	if err != nil {
+		return err
+	}
+	return re.MatchString(s[1])
+}
+
+// builtinRegexFind returns the first match of the pattern in the string, or nil.
+func builtinRegexFind(e *Evaluator, args []any) any {
+	s, err := stringArguments("regex_find", args, 2)
+	if err != nil {
+		return err
+	}
+	re, err := e.compileRegexp(s[0])
+	if err != nil {
+		return err
+	}
+	if match := re.FindStringIndex(s[1]); match != nil {
+		return s[1][match[0]:match[1]]
+	}
+	return nil
+}
+
+// builtinRegexReplace replaces every match of the pattern in the string. The replacement
+// can refer to the groups of the match with $1, $2, or ${name}.
+func builtinRegexReplace(e *Evaluator, args []any) any {
+	s, err := stringArguments("regex_replace", args, 3)
+	if err != nil {
+		return err
+	}
+	re, err := e.compileRegexp(s[0])
+	if err != nil {
+		return err
+	}
+	return re.ReplaceAllString(s[1], s[2])
+}
+
+func builtinBase64Encode(_ *Evaluator, args []any) any {
+	s, err := stringArguments("base64_encode", args, 1)
+	if err != nil {
+		return err
+	}
+	return base64.StdEncoding.EncodeToString([]byte(s[0]))
+}
+
+func builtinBase64Decode(_ *Evaluator, args []any) any {
+	s, err := stringArguments("base64_decode", args, 1)
+	if err != nil {
+		return err
+	}
+	decoded, err := base64.StdEncoding.DecodeString(s[0])
+	if err != nil {
+		return NewRuntimeError("base64_decode: %s", err)
+	}
+	return string(decoded)
+}
+
+func builtinHexEncode(_ *Evaluator, args []any) any {
+	s, err := stringArguments("hex_encode", args, 1)
+	if err != nil {
+		return err
+	}
+	return hex.EncodeToString([]byte(s[0]))
+}
+
+func builtinHexDecode(_ *Evaluator, args []any) any {
+	s, err := stringArguments("hex_decode", args, 1)
+	if err != nil {
+		return err
+	}
+	decoded, err := hex.DecodeString(s[0])
+	if err != nil {
+		return NewRuntimeError("hex_decode: %s", err)
+	}
+	return string(decoded)
+}
+
+// builtinHash makes a builtin that returns the hex-encoded digest of a string.
+func builtinHash(name string, newHash func() hash.Hash) Builtin {
+	return func(_ *Evaluator, args []any) any {
+		s, err := stringArguments(name, args, 1)
+		if err != nil {
+			return err
+		}
+		h := newHash()
+		h.Write([]byte(s[0]))
+		return hex.EncodeToString(h.Sum(nil))
+	}
+}
+
+// httpTimeout bounds how long http_get waits for a response, on top of the evaluation's
+// own context.
+const httpTimeout = 30 * time.Second
+
+// builtinHTTPGet fetches a URL and returns the body of the response. Network failures and
+// error statuses are runtime errors.
+func builtinHTTPGet(e *Evaluator, args []any) any {
+	s, err := stringArguments("http_get", args, 1)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(e.ctx, httpTimeout)
+	defer cancel()
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, s[0], nil)
+	if err != nil {
+		return NewRuntimeError("http_get: %s", err)
+	}
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return NewRuntimeError("http_get: %s", err)
+	}
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return NewRuntimeError("http_get: %s", err)
+	}
+	if response.StatusCode >= 400 {
+		return NewRuntimeError("http_get: %s returned %s", s[0], response.Status)
+	}
+	return string(body)
+}
+
+func builtinReadFile(_ *Evaluator, args []any) any {
+	s, err := stringArguments("read_file", args, 1)
+	if err != nil {
+		return err
+	}
+	content, err := os.ReadFile(s[0])
+	if err != nil {
+		return NewRuntimeError("read_file: %s", err)
+	}
+	return string(content)
+}
+
+// builtinWriteFile replaces the content of a file, creating it if it doesn't exist.
+func builtinWriteFile(_ *Evaluator, args []any) any {
+	s, err := stringArguments("write_file", args, 2)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(s[0], []byte(s[1]), 0644); err != nil {
+		return NewRuntimeError("write_file: %s", err)
+	}
+	return nil
+}
+
+// builtinInput prints the prompt, if there is one, and returns the next line of input
+// without its line ending. At the end of the input, it returns nil.
+func builtinInput(e *Evaluator, args []any) any {
+	if len(args) > 1 {
+		return NewRuntimeError("input expects at most 1 argument, got %d", len(args))
+	}
+	if len(args) == 1 {
+		fmt.Fprint(e.out, InspectPrecision(args[0], e.precision))
+	}
+	line, err := e.in.ReadString('\n')
+	if err == io.EOF && line == "" {
+		return nil
+	}
+	if err != nil && err != io.EOF {
+		return NewRuntimeError("input: %s", err)
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+}
+
+// builtinEnv returns the value of an environment variable, or nil when it isn't set.
+func builtinEnv(_ *Evaluator, args []any) any {
+	s, err := stringArguments("env", args, 1)
+	if err != nil {
+		return err
+	}
+	if value, ok := os.LookupEnv(s[0]); ok {
+		return value
+	}
+	return nil
+}
+
+// compileRegexp compiles a pattern once, and then takes it from a cache.
+func (e *Evaluator) compileRegexp(pattern string) (*regexp.Regexp, error) {
+	if re, ok := e.regexps[pattern]; ok {
+		return re, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, NewRuntimeError("invalid pattern %s: %s", strconv.Quote(pattern), err)
+	}
+	if e.regexps == nil {
+		e.regexps = make(map[string]*regexp.Regexp)
+	}
+	e.regexps[pattern] = re
+	return re, nil
+}
+
+// stringArguments checks that the builtin got exactly count strings.
+func stringArguments(name string, args []any, count int) ([]string, error) {
+	if len(args) != count {
+		return nil, NewRuntimeError("%s expects %d arguments, got %d", name, count, len(args))
+	}
+	strs := make([]string, len(args))
+	for i, arg := range args {
+		s, ok := arg.(string)
+		if !ok {
+			return nil, NewRuntimeError("%s expects strings, got %s", name, typeName(arg))
+		}
+		strs[i] = s
+	}
+	return strs, nil
+}
+
+// builtinPopcount counts the bits set in the absolute value of an int.
+func builtinPopcount(_ *Evaluator, args []any) any {
+	n, err := intArguments("popcount", args, 1)
+	if err != nil {
+		return err
+	}
+	return int64(bits.OnesCount64(absInt(n[0])))
+}
+
+// builtinBitLength returns the number of bits needed to represent the absolute value of
+// an int, which is 0 for 0.
+func builtinBitLength(_ *Evaluator, args []any) any {
+	n, err := intArguments("bit_length", args, 1)
+	if err != nil {
+		return err
+	}
+	return int64(bits.Len64(absInt(n[0])))
+}
+
+// builtinGcd returns the greatest common divisor of two ints, which is never negative.
+func builtinGcd(_ *Evaluator, args []any) any {
+	n, err := intArguments("gcd", args, 2)
+	if err != nil {
+		return err
+	}
+	result := gcd(absInt(n[0]), absInt(n[1]))
+	if result > math.MaxInt64 {
+		return NewRuntimeError("gcd of %d and %d is out of the range of an int", n[0], n[1])
+	}
+	return int64(result)
+}
+
+// builtinLcm returns the least common multiple of two ints, which is 0 when either is 0.
+func builtinLcm(_ *Evaluator, args []any) any {
+	n, err := intArguments("lcm", args, 2)
+	if err != nil {
+		return err
+	}
+	a, b := absInt(n[0]), absInt(n[1])
+	if a == 0 || b == 0 {
+		return int64(0)
+	}
+	a /= gcd(a, b)
+	if a > math.MaxInt64/b {
+		return NewRuntimeError("lcm of %d and %d is out of the range of an int", n[0], n[1])
+	}
+	return int64(a * b)
+}
+
+// intArguments checks that the builtin got exactly count ints.
+func intArguments(name string, args []any, count int) ([]int64, error) {
+	if len(args) != count {
+		return nil, NewRuntimeError("%s expects %d arguments, got %d", name, count, len(args))
+	}
+	ints := make([]int64, len(args))
+	for i, arg := range args {
+		n, ok := arg.(int64)
+		if !ok {
+			return nil, NewRuntimeError("%s expects ints, got %s", name, typeName(arg))
+		}
+		ints[i] = n
+	}
+	return ints, nil
+}
+
+// absInt returns the absolute value of n as a uint64, which, unlike an int64, can hold the
+// absolute value of math.MinInt64.
+func absInt(n int64) uint64 {
+	if n == math.MinInt64 {
+		return 1 << 63
+	}
+	if n < 0 {
+		return uint64(-n)
+	}
+	return uint64(n)
+}
+
+func gcd(a uint64, b uint64) uint64 {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+func (e *Evaluator) passAssertion() any {
+	if e.tests != nil {
+		e.tests.Passed++
+	}
+	return nil
+}
+
+func (e *Evaluator) failAssertion(message string) any {
+	if e.tests == nil {
+		return NewRuntimeError("%s", message)
+	}
+	e.tests.Failed++
+	e.tests.Failures = append(e.tests.Failures, message)
+	return nil
+}
+
+// deepEqual compares two values the way == does, looking into arrays and maps item by
+// item. Values of different types are never equal, except for ints and floats.
+func deepEqual(left any, right any) bool {
+	// floats are compared with == rather than ordered, so that NaN isn't equal to itself
+	switch l := left.(type) {
+	case float64:
+		switch r := right.(type) {
+		case float64:
+			return l == r
+		case int64:
+			return l == float64(r)
+		}
+	case int64:
+		if r, ok := right.(float64); ok {
+			return float64(l) == r
+		}
+	}
+	if result, ok := compareValues(left, right); ok {
+		return result == 0
+	}
+	switch l := left.(type) {
+	case nil:
+		return right == nil
+	case bool:
+		r, ok := right.(bool)
+		return ok && l == r
+	case []any:
+		r, ok := right.([]any)
+		if !ok || len(l) != len(r) {
+			return false
+		}
+		for i := range l {
+			if !deepEqual(l[i], r[i]) {
+				return false
+			}
+		}
+		return true
+	case map[any]any:
+		r, ok := right.(map[any]any)
+		if !ok || len(l) != len(r) {
+			return false
+		}
+		for key, value := range l {
+			other, found := r[key]
+			if !found || !deepEqual(value, other) {
+				return false
+			}
+		}
+		return true
+	case Function:
+		r, ok := right.(Function)
+		return ok && sameFunction(l, r)
+	default:
+		return false
+	}
+}
+
+// typeName is the name of the value's type as the user knows it.
+func typeName(value any) string {
+	switch value.(type) {
+	case nil:
+		return "nil"
+	case bool:
+		return "bool"
+	case int64:
+		return "int"
+	case float64:
+		return "float"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[any]any:
+		return "map"
+	case Function, Partial, Composition, Memo, Native:
+		return "function"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+func toFloat(value any) float64 {
+	switch v := value.(type) {
+	case int64:
+		return float64(v)
+	case float64:
+		return v
+	default:
+		return 0
+	}
+}
+
+// compareValues orders two numbers, or two strings. The second result is false when the
+// values can't be ordered against each other.
+func compareValues(left any, right any) (int, bool) {
+	switch l := left.(type) {
+	case int64:
+		switch r := right.(type) {
+		case int64:
+			return compareOrdered(l, r), true
+		case float64:
+			return compareOrdered(float64(l), r), true
+		}
+	case float64:
+		switch r := right.(type) {
+		case int64:
+			return compareOrdered(l, float64(r)), true
+		case float64:
+			return compareOrdered(l, r), true
+		}
+	case string:
+		if r, ok := right.(string); ok {
+			return compareOrdered(l, r), true
+		}
+	}
+	return 0, false
+}
+
+func compareOrdered[T int64 | float64 | string](left T, right T) int {
+	switch {
+	case left < right:
+		return -1
+	case left > right:
+		return 1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,1840 @@
+package uni
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEval(t *testing.T) {
+	tt := []struct {
+		name string
+		in   string
+		want any
+	}{
+		{
+			name: "",
+			in: `fn fibonacci(n) {
+					if n == 1 {
+						return 0
+					}
+					if n == 2 {
+						return 1
+					}
+					return fibonacci(n-1) + fibonacci(n-2)
+				}	
+				fibonacci(3)		
+			`,
+			want: int64(1),
+		},
+		{
+			name: "empty program",
+			in:   "",
+			want: nil,
+		},
+		{
+			name: "whitespace only program",
+			in:   " \t\r\n\n ",
+			want: nil,
+		},
+		{
+			name: "comment only program",
+			in:   "# first\n# second\n#",
+			want: nil,
+		},
+		{
+			name: "last statement value",
+			in:   "var a = 1\nvar b = 2\na + b\nvar c = 3",
+			want: nil,
+		},
+		{
+			name: "every statement is evaluated",
+			in:   "var a = 1\nvar b = 2\nvar c = 3\na + b + c",
+			want: int64(6),
+		},
+		{
+			name: "if block variable is invisible outside",
+			in:   "if true { var b = 2 }\nb",
+			want: NewRuntimeError("undefined variable b"),
+		},
+		{
+			name: "else block variable is invisible outside",
+			in:   "if false {} else { var b = 2 }\nb",
+			want: NewRuntimeError("undefined variable b"),
+		},
+		{
+			name: "bare block variable is invisible outside",
+			in:   "{ var b = 2 }\nb",
+			want: NewRuntimeError("undefined variable b"),
+		},
+		{
+			name: "loop variables are invisible outside",
+			in:   "for k, v in [1] { var b = v }\nv",
+			want: NewRuntimeError("undefined variable v"),
+		},
+		{
+			name: "loop body variables are invisible outside",
+			in:   "for k, v in [1] { var b = v }\nb",
+			want: NewRuntimeError("undefined variable b"),
+		},
+		{
+			name: "function parameters are invisible outside",
+			in:   "fn f(a) { var b = a }\nf(1)\na",
+			want: NewRuntimeError("undefined variable a"),
+		},
+		{
+			name: "function variables are invisible outside",
+			in:   "fn f(a) { var b = a }\nf(1)\nb",
+			want: NewRuntimeError("undefined variable b"),
+		},
+		{
+			name: "undefined function",
+			in:   "foo(1)",
+			want: NewRuntimeError("undefined function foo"),
+		},
+		{
+			name: "shadowing restores the outer value",
+			in:   "var a = 1\nif true { var a = 2 }\n{ var a = 3 }\nfor k, v in [4] { var a = v }\na",
+			want: int64(1),
+		},
+		{
+			name: "shadowed value is visible inside the block",
+			in:   "var a = 1\nfn f() { var a = 2\nif true { return a } }\nf()",
+			want: int64(2),
+		},
+		{
+			name: "global counter",
+			in: `var counter = 0
+				fn increment(by) {
+					global counter = counter + by
+				}
+				increment(1)
+				increment(41)
+				counter
+			`,
+			want: int64(42),
+		},
+		{
+			name: "global declaration",
+			in:   "fn setup() { if true { global ready = true } }\nsetup()\nready",
+			want: true,
+		},
+		{
+			name: "negative map key",
+			in:   `var m = {-1: "x", 1: "y"}` + "\n" + `[m[-1], m[1], m[0 - 1]]`,
+			want: []any{"x", "y", "x"},
+		},
+		{
+			name: "negative array items",
+			in:   `[-1, -2.5, --3]`,
+			want: []any{int64(-1), -2.5, int64(3)},
+		},
+		{
+			name: "and binds tighter than or",
+			in:   "[true or true and false, false and true or true]",
+			want: []any{true, true},
+		},
+		{
+			name: "comparisons bind tighter than and, or",
+			in:   "var a = 1\nvar b = 2\n[a == 1 and b == 2, a == 2 or b != 2, a < b and b > a, a == 1 and b == 3]",
+			want: []any{true, false, true, false},
+		},
+		{
+			name: "arithmetic precedence",
+			in:   "[2 + 3 * 4 - 1, 1 - 2 - 3, 8 / 4 / 2, -(1 + 2) * 3]",
+			want: []any{int64(13), int64(-4), int64(1), int64(-9)},
+		},
+		{
+			name: "chained index",
+			in:   `var data = {"users": [{"name": "ada"}, {"name": "bob"}]}` + "\n" + `data["users"][1]["name"]`,
+			want: "bob",
+		},
+		{
+			name: "index of call",
+			in:   "fn list() { return [[1, 2], [3, 4]] }\nlist()[1][0]",
+			want: int64(3),
+		},
+		{
+			name: "sorted map keys",
+			in:   `sorted({"b": 2, "c": 3, "a": 1})`,
+			want: []any{"a", "b", "c"},
+		},
+		{
+			name: "for over sorted map keys",
+			in:   "var m = {\"b\": 2, \"c\": 3, \"a\": 1}\nvar s = \"\"\nfor _, k in sorted(m) { s = s + k + str(m[k]) }\ns",
+			want: "a1b2c3",
+		},
+		{
+			name: "reading throwaway",
+			in:   "var _ = 1\nvar x = _\nx",
+			want: NewRuntimeError("cannot read from _"),
+		},
+		{
+			name: "throwaway function parameter",
+			in:   "fn second(_, b) { return b }\nsecond(1, 2)",
+			want: int64(2),
+		},
+		{
+			name: "clamp below",
+			in:   "clamp(-5, 0, 10)",
+			want: int64(0),
+		},
+		{
+			name: "clamp within",
+			in:   "clamp(5, 0, 10)",
+			want: int64(5),
+		},
+		{
+			name: "clamp above",
+			in:   "clamp(15, 0, 10)",
+			want: int64(10),
+		},
+		{
+			name: "clamp float",
+			in:   "[clamp(2.5, 0, 1), clamp(5, 0, 7.5)]",
+			want: []any{float64(1), float64(5)},
+		},
+		{
+			name: "clamp empty range",
+			in:   "clamp(5, 10, 0)",
+			want: NewRuntimeError("clamp lower bound 10 is greater than upper bound 0"),
+		},
+		{
+			name: "clamp string",
+			in:   `clamp("a", 0, 1)`,
+			want: NewRuntimeError("clamp expects numbers, got string"),
+		},
+		{
+			name: "sign",
+			in:   "[sign(-3), sign(0), sign(7), sign(-0.5), sign(0.0), sign(2.5)]",
+			want: []any{int64(-1), int64(0), int64(1), int64(-1), int64(0), int64(1)},
+		},
+		{
+			name: "trunc",
+			in:   "[trunc(-2.7), trunc(0.0), trunc(2.7), trunc(5)]",
+			want: []any{int64(-2), int64(0), int64(2), int64(5)},
+		},
+		{
+			name: "abs",
+			in:   "[abs(-3), abs(3), abs(-2.5), abs(0.0)]",
+			want: []any{int64(3), int64(3), 2.5, 0.0},
+		},
+		{
+			name: "abs of the smallest int",
+			in:   "abs(-9223372036854775807 - 1)",
+			want: NewRuntimeError("abs of -9223372036854775808 is out of the range of an int"),
+		},
+		{
+			name: "min and max",
+			in:   "[min(3, 1, 2), max(3, 1, 2), min(2, 1.5), max(2, 2.0), min(7), max(-1, -0.5)]",
+			want: []any{int64(1), int64(3), 1.5, int64(2), int64(7), -0.5},
+		},
+		{
+			name: "min of nothing",
+			in:   "min()",
+			want: NewRuntimeError("min expects at least 1 argument, got 0"),
+		},
+		{
+			name: "max of a string",
+			in:   `max(1, "2")`,
+			want: NewRuntimeError("max expects numbers, got string"),
+		},
+		{
+			name: "sqrt",
+			in:   "[sqrt(16), sqrt(2.25), sqrt(0)]",
+			want: []any{4.0, 1.5, 0.0},
+		},
+		{
+			name: "sqrt of a negative number",
+			in:   "sqrt(0 - 4)",
+			want: NewRuntimeError("sqrt of negative number -4"),
+		},
+		{
+			name: "floor, ceil, and round",
+			in:   "[floor(2.7), floor(0 - 2.2), ceil(2.2), ceil(0 - 2.7), round(2.5), round(0 - 2.5), round(2.4), floor(3)]",
+			want: []any{int64(2), int64(-3), int64(3), int64(-2), int64(3), int64(-3), int64(2), int64(3)},
+		},
+		{
+			name: "round a string",
+			in:   `round("1.5")`,
+			want: NewRuntimeError("round expects a number, got string"),
+		},
+		{
+			name: "trunc out of range",
+			in:   "var a = trunc(1e300)",
+			want: NewRuntimeError("trunc of 1e+300 is out of the range of an int"),
+		},
+		{
+			name: "trunc infinity",
+			in:   "var a = trunc(-1 / 0.0)",
+			want: NewRuntimeError("trunc of -Inf is out of the range of an int"),
+		},
+		{
+			name: "round NaN",
+			in:   "var a = round(0 / 0.0)",
+			want: NewRuntimeError("round of NaN is out of the range of an int"),
+		},
+		{
+			name: "trunc string",
+			in:   `trunc("1.5")`,
+			want: NewRuntimeError("trunc expects a number, got string"),
+		},
+		{
+			name: "popcount and bit_length",
+			in:   "[popcount(0), popcount(7), popcount(-8), bit_length(0), bit_length(1), bit_length(255), bit_length(256)]",
+			want: []any{int64(0), int64(3), int64(1), int64(0), int64(1), int64(8), int64(9)},
+		},
+		{
+			name: "gcd and lcm",
+			in:   "[gcd(12, 18), gcd(-4, 6), gcd(0, 5), lcm(4, 6), lcm(-3, 5), lcm(0, 5)]",
+			want: []any{int64(6), int64(2), int64(5), int64(12), int64(15), int64(0)},
+		},
+		{
+			name: "gcd of the smallest int",
+			in:   "gcd(-9223372036854775807 - 1, 0)",
+			want: NewRuntimeError("gcd of -9223372036854775808 and 0 is out of the range of an int"),
+		},
+		{
+			name: "gcd of the smallest int and another int",
+			in:   "gcd(-9223372036854775807 - 1, 6)",
+			want: int64(2),
+		},
+		{
+			name: "lcm out of range",
+			in:   "lcm(9223372036854775807, 2)",
+			want: NewRuntimeError("lcm of 9223372036854775807 and 2 is out of the range of an int"),
+		},
+		{
+			name: "gcd float",
+			in:   "gcd(12, 1.5)",
+			want: NewRuntimeError("gcd expects ints, got float"),
+		},
+		{
+			name: "str",
+			in:   `[str(12), str("a"), str([1, [2, 3], {"k": 4}])]`,
+			want: []any{"12", "a", `[1, [2, 3], {"k": 4}]`},
+		},
+		{
+			name: "function equality",
+			in: `fn add(a, b) { return a + b }
+				fn plus(a, b) { return a + b }
+				var f = add
+				var g = add
+				var r = [f == g, f != g, f == plus, add == plus]
+				r`,
+			want: []any{true, false, false, false},
+		},
+		{
+			name: "function compared with an int",
+			in:   "fn f() {}\nvar g = f\ng == 1",
+			want: NewRuntimeError("cannot compare function and int"),
+		},
+		{
+			name: "function in a variable",
+			in: `fn add(a, b) { return a + b }
+				var f = add
+				var g = f
+				g(f(1, 2), 3)`,
+			want: int64(6),
+		},
+		{
+			name: "function as a callback",
+			in: `fn twice(f, x) { return f(f(x)) }
+				fn square(x) { return x * x }
+				twice(square, 3)`,
+			want: int64(81),
+		},
+		{
+			name: "function in a container",
+			in: `fn add(a, b) { return a + b }
+				fn sub(a, b) { return a - b }
+				var ops = {"add": add, "sub": [sub]}
+				ops["add"](ops["sub"][0](10, 4), 1)`,
+			want: int64(7),
+		},
+		{
+			name: "partial",
+			in: `fn add(a, b) { return a + b }
+				var add5 = partial(add, 5)
+				var add5and1 = partial(add5, 1)
+				var r = [add5(3), partial(add, 1, 2)(), add5and1(), str(add5)]
+				r`,
+			want: []any{int64(8), int64(3), int64(6), "partial(fn add(a, b), 5)"},
+		},
+		{
+			name: "partial of a non-function",
+			in:   "partial(1, 2)",
+			want: NewRuntimeError("partial expects a function, got int"),
+		},
+		{
+			name: "compose",
+			in: `fn double(x) { return x * 2 }
+				fn increment(x) { return x + 1 }
+				fn add(a, b) { return a + b }
+				var h = compose(double, increment)
+				var r = [h(5), compose(increment, double)(5), compose(double, increment, add)(1, 2), str(h)]
+				r`,
+			want: []any{int64(12), int64(11), int64(8), "compose(fn double(x), fn increment(x))"},
+		},
+		{
+			name: "compose of a non-function",
+			in:   "fn f(x) { return x }\ncompose(f, 1)",
+			want: NewRuntimeError("compose expects functions, got int"),
+		},
+		{
+			name: "memo",
+			in: `var calls = 0
+				fn slow(n) {
+					global calls = calls + 1
+					if n < 2 {
+						return n
+					}
+					return fast(n - 1) + fast(n - 2)
+				}
+				var fast = memo(slow)
+				var r = [fast(30), calls, fast(30), calls]
+				r`,
+			want: []any{int64(832040), int64(31), int64(832040), int64(31)},
+		},
+		{
+			name: "memo with an array argument",
+			in:   "fn first(a) { return a[0] }\nmemo(first)([1])",
+			want: NewRuntimeError("memo cannot remember calls with array arguments"),
+		},
+		{
+			name: "memo with a comma in a string argument",
+			in:   "fn id(a) { return a }\nvar m = memo(id)\nm(\"a,string:b\")\nm(\"a\", \"b\")",
+			want: NewRuntimeError("id expects 1 argument, got 2"),
+		},
+		{
+			name: "version",
+			in:   "version()",
+			want: Version,
+		},
+		{
+			name: "container equality",
+			in: `var a = [1, [2, {"k": [3]}]]
+				var r = [a == [1, [2, {"k": [3]}]], a == [1, [2, {"k": [4]}]], a != [1], {"x": 1} == {"x": 1.0}]
+				r`,
+			want: []any{true, false, true, true},
+		},
+		{
+			name: "array compared with a map",
+			in:   "var a = []\na == {}",
+			want: NewRuntimeError("cannot compare array and map"),
+		},
+		{
+			name: "deep_equal",
+			in: `var r = [deep_equal([1, {"a": [2]}], [1, {"a": [2]}]), deep_equal({"a": 1}, {"a": 1, "b": 2}), deep_equal([], {}), deep_equal(1, "1"), deep_equal(1, 1.0)]
+				r`,
+			want: []any{true, false, false, false, true},
+		},
+		{
+			name: "nan in containers",
+			in:   "var x = 0 / 0.0\nvar r = [x == x, [x] == [x], [x] != [x], deep_equal({\"a\": x}, {\"a\": x})]\nr",
+			want: []any{false, false, true, false},
+		},
+		{
+			name: "freeze",
+			in: `var config = freeze({"name": "uni", "ports": [80, 443]})
+				var other = {"ports": [80, 443]}
+				var r = [config["ports"][1], config["name"], is_frozen(config), is_frozen(config["ports"]), is_frozen(other), is_frozen(other["ports"])]
+				r`,
+			want: []any{int64(443), "uni", true, true, false, false},
+		},
+		{
+			name: "array as a map literal key",
+			in:   "var m = {[1]: 2}",
+			want: NewRuntimeError("cannot use array as a map key"),
+		},
+		{
+			name: "function as a map literal key",
+			in:   "var m = {\"a\": 1, fn(x) { return x }: 1}",
+			want: NewRuntimeError("cannot use function as a map key"),
+		},
+		{
+			name: "map literal keys in order",
+			in:   "var m = {1: \"a\", 1.0: \"b\", \"k\": {}}\nm",
+			want: map[any]any{int64(1): "a", 1.0: "b", "k": map[any]any{}},
+		},
+		{
+			name: "freeze an empty array",
+			in:   "var a = []\nfreeze(a)\nvar b = []\nvar r = [is_frozen(a), is_frozen(b), is_frozen(freeze(keys({}))), is_frozen(a[0:0])]\nr",
+			want: []any{true, false, true, false},
+		},
+		{
+			name: "freeze a number",
+			in:   "freeze(1)",
+			want: NewRuntimeError("freeze expects an array or a map, got int"),
+		},
+		{
+			name: "chained variables",
+			in: `var calls = 0
+				fn next() {
+					global calls = calls + 1
+					return calls * 10
+				}
+				var a = b = next()
+				var r1 = [a, b, calls]
+				a = b = 5
+				var r2 = [a, b]
+				fn reset() {
+					global a = b = 0
+				}
+				reset()
+				var r = [r1, r2, [a, b]]
+				r`,
+			want: []any{[]any{int64(10), int64(10), int64(1)}, []any{int64(5), int64(5)}, []any{int64(0), int64(0)}},
+		},
+		{
+			name: "bare block has no value",
+			in:   "{ 1 + 2 }",
+			want: nil,
+		},
+		{
+			name: "expression statement is not a return",
+			in:   "fn f() {\n7\nreturn 3\n}\nf()",
+			want: int64(3),
+		},
+		{
+			name: "function without return",
+			in:   "fn f() { 5 }\nf()",
+			want: nil,
+		},
+		{
+			name: "return from nested blocks",
+			in:   "fn f(x) {\nif x > 0 {\n{ return 1 }\n}\nreturn 2\n}\nvar r = [f(1), f(0)]\nr",
+			want: []any{int64(1), int64(2)},
+		},
+		{
+			name: "return at top level",
+			in:   "1\nreturn 2\n3",
+			want: int64(2),
+		},
+		{
+			name: "trim_prefix and trim_suffix",
+			in:   `[trim_prefix("unicode", "uni"), trim_prefix("unicode", "code"), trim_suffix("main.uni", ".uni"), trim_suffix("main.uni", ".go"), trim_prefix("", "a")]`,
+			want: []any{"code", "unicode", "main", "main.uni", ""},
+		},
+		{
+			name: "upper, lower, and trim",
+			in:   `[upper("Héllo 1"), lower("HÉLLO"), trim(" \t a b \n"), trim("")]`,
+			want: []any{"HÉLLO 1", "héllo", "a b", ""},
+		},
+		{
+			name: "replace and contains",
+			in:   `[replace("a-b-c", "-", "+"), replace("abc", "x", "y"), contains("unicode", "code"), contains("uni", "x"), contains("uni", "")]`,
+			want: []any{"a+b+c", "abc", true, false, true},
+		},
+		{
+			name: "upper of a number",
+			in:   `upper(1)`,
+			want: NewRuntimeError("upper expects strings, got int"),
+		},
+		{
+			name: "replace with a missing argument",
+			in:   `replace("abc", "a")`,
+			want: NewRuntimeError("replace expects 3 arguments, got 2"),
+		},
+		{
+			name: "contains in an array",
+			in:   `contains(["a"], "a")`,
+			want: NewRuntimeError("contains expects strings, got array"),
+		},
+		{
+			name: "trim_prefix of a number",
+			in:   `trim_prefix(1, "a")`,
+			want: NewRuntimeError("trim_prefix expects strings, got int"),
+		},
+		{
+			name: "regex",
+			in: `var r = [
+					regex_match("^[a-z]+[0-9]*$", "uni42"),
+					regex_match("^[0-9]+$", "uni42"),
+					regex_find("[0-9]+", "v1.25"),
+					regex_find("[0-9]+", "none"),
+					regex_replace("([a-z]+)@([a-z]+)", "ada@home bob@work", "$2:$1"),
+					regex_replace("a+", "banana", "o"),
+				]
+				r`,
+			want: []any{true, false, "1", nil, "home:ada work:bob", "bonono"},
+		},
+		{
+			name: "invalid regex",
+			in:   `regex_match("(", "a")`,
+			want: NewRuntimeError("invalid pattern \"(\": error parsing regexp: missing closing ): `(`"),
+		},
+		{
+			name: "base64 and hex",
+			in: `var r = [
+					base64_encode("Hello, Uni!"),
+					base64_decode(base64_encode("Hello, Uni!")),
+					base64_encode(""),
+					hex_encode("uni"),
+					hex_decode(hex_encode("Hello, Uni!")),
+				]
+				r`,
+			want: []any{"SGVsbG8sIFVuaSE=", "Hello, Uni!", "", "756e69", "Hello, Uni!"},
+		},
+		{
+			name: "invalid base64",
+			in:   `base64_decode("not base64!")`,
+			want: NewRuntimeError("base64_decode: illegal base64 data at input byte 3"),
+		},
+		{
+			name: "invalid hex",
+			in:   `hex_decode("zz")`,
+			want: NewRuntimeError("hex_decode: encoding/hex: invalid byte: U+007A 'z'"),
+		},
+		{
+			name: "hashes",
+			in:   `[md5("uni"), sha1("uni"), sha256("uni"), md5("")]`,
+			want: []any{
+				"e52805d8344b67b9b3554d45f1c8958f",
+				"f73373ed1a26a50ab84500f661b715ececc261b5",
+				"8d9315fc224d877fa15d9322ef9d26e9f6bf233d9d2290fdab0c232494bc945c",
+				"d41d8cd98f00b204e9800998ecf8427e",
+			},
+		},
+		{
+			name: "typematch",
+			in: `fn describe(x) {
+					typematch x {
+						int, float: { return "number" }
+						string: { return "string " + x }
+						array: { return "array" }
+						map: { return "map" }
+						else: { return type(x) }
+					}
+				}
+				fn f() {}
+				var r = [describe(1), describe(2.5), describe("a"), describe([1]), describe({}), describe(true), describe(f), describe(f())]
+				r`,
+			want: []any{"number", "number", "string a", "array", "map", "bool", "function", "nil"},
+		},
+		{
+			name: "typematch without a match",
+			in:   "typematch 1 { string: { 2 } }",
+			want: nil,
+		},
+		{
+			name: "sorted array",
+			in:   `sorted([3, 1.5, 2])`,
+			want: []any{1.5, int64(2), int64(3)},
+		},
+		{
+			name: "while body ending in an expression",
+			in:   "fn count() {\nvar i = 0\nwhile i < 3 {\ni = i + 1\ni * 10\n}\nreturn i\n}\ncount()",
+			want: int64(3),
+		},
+		{
+			name: "return from while",
+			in:   "fn first() {\nvar i = 0\nwhile true {\ni = i + 1\nif i == 2 { return i }\n}\n}\nfirst()",
+			want: int64(2),
+		},
+		{
+			name: "for body ending in an expression",
+			in:   "fn total(items) {\nvar sum = 0\nfor _, v in items {\nsum = sum + v\nsum\n}\nreturn sum\n}\ntotal([1, 2, 3])",
+			want: int64(6),
+		},
+		{
+			name: "for over a map body ending in an expression",
+			in:   "fn count(m) {\nvar n = 0\nfor k, v in m {\nn = n + 1\nv\n}\nreturn n\n}\ncount({\"a\": 1, \"b\": 2})",
+			want: int64(2),
+		},
+		{
+			name: "for over a string body ending in an expression",
+			in:   "fn count(s) {\nvar n = 0\nfor k, v in s {\nn = n + 1\nv\n}\nreturn n\n}\ncount(\"abc\")",
+			want: int64(3),
+		},
+		{
+			name: "return from for",
+			in:   "fn find(items, x) {\nfor k, v in items {\nif v == x { return v }\n}\nreturn -1\n}\nfind([4, 5, 6], 5)",
+			want: int64(5),
+		},
+		{
+			name: "anonymous function in a variable",
+			in:   "var add = fn(a, b) { return a + b }\nadd(1, 2)",
+			want: int64(3),
+		},
+		{
+			name: "anonymous function as an argument",
+			in:   "fn apply(f, x) { return f(x) }\napply(fn(x) { return x * 10 }, 4)",
+			want: int64(40),
+		},
+		{
+			name: "anonymous function called right away",
+			in:   "fn(x) { return x + 1 }(1)",
+			want: int64(2),
+		},
+		{
+			name: "closure",
+			in:   "fn adder(n) { return fn(x) { return x + n } }\nvar add2 = adder(2)\nvar add5 = adder(5)\nvar r = [add2(1), add5(1), add2 == add2, add2 == add5]\nr",
+			want: []any{int64(3), int64(6), true, false},
+		},
+		{
+			name: "closure sharing a variable",
+			in:   "fn counter() {\nvar count = 0\nreturn fn() {\ncount = count + 1\nreturn count\n}\n}\nvar next = counter()\nnext()\nnext()\nnext()",
+			want: int64(3),
+		},
+		{
+			name: "anonymous function rendered",
+			in:   "str(fn(a, b) {})",
+			want: "fn(a, b)",
+		},
+		{
+			name: "else if",
+			in:   "fn grade(n) {\nif n > 90 { return \"a\" } else if n > 80 { return \"b\" } else if n > 70 { return \"c\" } else { return \"f\" }\n}\n[grade(95), grade(85), grade(75), grade(10)]",
+			want: []any{"a", "b", "c", "f"},
+		},
+		{
+			name: "else if without else",
+			in:   "var a = 0\nif false { a = 1 } else if false { a = 2 }\na",
+			want: int64(0),
+		},
+		{
+			name: "index assignment",
+			in:   "var a = [1, [2, 3]]\nvar b = a\na[0] = \"one\"\nb[1][0] = 4 * 5\na",
+			want: []any{"one", []any{int64(20), int64(3)}},
+		},
+		{
+			name: "index assignment out of range",
+			in:   "var a = [1]\na[1] = 2",
+			want: NewRuntimeError("index 1 out of range for array of length 1"),
+		},
+		{
+			name: "negative index",
+			in:   "var a = [1, 2, 3]\nvar r = [a[-1], a[-3], a[0 - len(a) + 1]]\nr",
+			want: []any{int64(3), int64(1), int64(2)},
+		},
+		{
+			name: "index out of range",
+			in:   "var a = [1, 2, 3]\na[3]",
+			want: NewRuntimeError("index 3 out of range for array of length 3"),
+		},
+		{
+			name: "negative index out of range",
+			in:   "var a = [1, 2, 3]\na[-4]",
+			want: NewRuntimeError("index -4 out of range for array of length 3"),
+		},
+		{
+			name: "string index",
+			in:   "var s = \"héllo\"\nvar r = [s[0], s[1], s[-1], type(s[1])]\nr",
+			want: []any{"h", "é", "o", "string"},
+		},
+		{
+			name: "string index out of range",
+			in:   "var s = \"héllo\"\ns[5]",
+			want: NewRuntimeError("index 5 out of range for string of length 5"),
+		},
+		{
+			name: "string index that isn't an int",
+			in:   "var s = \"abc\"\ns[\"a\"]",
+			want: NewRuntimeError("string index must be an int, got string"),
+		},
+		{
+			name: "string index assignment",
+			in:   "var s = \"abc\"\ns[0] = \"x\"",
+			want: NewRuntimeError("cannot assign to an index of string"),
+		},
+		{
+			name: "index assignment with a negative index",
+			in:   "var a = [1, 2, 3]\na[-1] = 30\na",
+			want: []any{int64(1), int64(2), int64(30)},
+		},
+		{
+			name: "negative index assignment out of range",
+			in:   "var a = [1]\na[-2] = 2",
+			want: NewRuntimeError("index -2 out of range for array of length 1"),
+		},
+		{
+			name: "index assignment with a float index",
+			in:   "var a = [1]\na[0.0] = 2",
+			want: NewRuntimeError("array index must be an int, got float"),
+		},
+		{
+			name: "index assignment to a frozen array",
+			in:   "var a = freeze([[1]])\na[0][0] = 2",
+			want: NewRuntimeError("cannot change a frozen array"),
+		},
+		{
+			name: "map assignment and delete",
+			in:   "var m = {\"a\": 1}\nm[\"b\"] = 2\nm[\"a\"] = m[\"a\"] + 10\nm[3] = [m[\"b\"]]\ndelete(m, \"b\")\ndelete(m, \"missing\")\nm",
+			want: map[any]any{"a": int64(11), int64(3): []any{int64(2)}},
+		},
+		{
+			name: "map assignment with an array key",
+			in:   "var m = {}\nm[[1]] = 2",
+			want: NewRuntimeError("cannot use array as a map key"),
+		},
+		{
+			name: "map index with an array key",
+			in:   "var m = {}\nm[[1]]",
+			want: NewRuntimeError("cannot use array as a map key"),
+		},
+		{
+			name: "map assignment to a frozen map",
+			in:   "var m = freeze({\"k\": {}})\nm[\"k\"][\"x\"] = 1",
+			want: NewRuntimeError("cannot change a frozen map"),
+		},
+		{
+			name: "delete from a frozen map",
+			in:   "delete(freeze({\"k\": 1}), \"k\")",
+			want: NewRuntimeError("cannot change a frozen map"),
+		},
+		{
+			name: "map containing itself",
+			in:   "var m = {\"n\": 1}\nm[\"self\"] = m\nstr(m)",
+			want: `{"n": 1, "self": {...}}`,
+		},
+		{
+			name: "split",
+			in:   `var r = [split("a,b,c", ","), split("abc", ""), split("", ","), split("a, b", ", ")]` + "\nr",
+			want: []any{[]any{"a", "b", "c"}, []any{"a", "b", "c"}, []any{""}, []any{"a", "b"}},
+		},
+		{
+			name: "join",
+			in:   `var r = [join(["a", "b"], "-"), join([], ","), join([1, 2.5, "x", [true]], ", ")]` + "\nr",
+			want: []any{"a-b", "", "1, 2.5, x, [true]"},
+		},
+		{
+			name: "join with a non-array",
+			in:   `join("abc", ",")`,
+			want: NewRuntimeError("join expects an array, got string"),
+		},
+		{
+			name: "keys and values",
+			in:   `var m = {"a": 1, "b": 2, "c": 3}` + "\nvar r = [sorted(keys(m)), sorted(values(m)), keys({}), values({})]\nr",
+			want: []any{[]any{"a", "b", "c"}, []any{int64(1), int64(2), int64(3)}, []any{}, []any{}},
+		},
+		{
+			name: "keys of an array",
+			in:   "keys([1])",
+			want: NewRuntimeError("keys expects a map, got array"),
+		},
+		{
+			name: "values with two maps",
+			in:   "values({}, {})",
+			want: NewRuntimeError("values expects 1 argument, got 2"),
+		},
+		{
+			name: "push",
+			in:   "var a = [1]\nvar b = push(a, 2)\nvar c = push(b, 3, [4])\nvar r = [a, b, c, push([], \"x\")]\nr",
+			want: []any{[]any{int64(1)}, []any{int64(1), int64(2)}, []any{int64(1), int64(2), int64(3), []any{int64(4)}}, []any{"x"}},
+		},
+		{
+			name: "push to a frozen array",
+			in:   "push(freeze([1]), 2)",
+			want: []any{int64(1), int64(2)},
+		},
+		{
+			name: "push to a non-array",
+			in:   `push("ab", "c")`,
+			want: NewRuntimeError("push expects an array, got string"),
+		},
+		{
+			name: "assignment changes only the nearest variable",
+			in: `var a = 1
+				var seen = []
+				if true {
+					var a = 2
+					if true {
+						a = 3
+						seen = push(seen, a)
+					}
+					seen = push(seen, a)
+				}
+				push(seen, a)`,
+			want: []any{int64(3), int64(3), int64(1)},
+		},
+		{
+			name: "classic for scopes its variable",
+			in:   "var i = 10\nfor i = 0; i < 3; i = i + 1 {}\ni",
+			want: int64(10),
+		},
+		{
+			name: "scientific notation",
+			in:   "var r = [1e3, 2.5e-3, 1E+2 * 2, 1e3 == 1000]\nr",
+			want: []any{1000.0, 0.0025, 200.0, true},
+		},
+		{
+			name: "digit separators",
+			in:   "var r = [1_000 * 2, 0.000_5, 1_0..1_2]\nr",
+			want: []any{int64(2000), 0.0005, []any{int64(10), int64(11)}},
+		},
+		{
+			name: "bare return",
+			in: `var log = []
+				fn f(a) {
+					if a > 1 {
+						return
+					}
+					log = push(log, a)
+				}
+				f(1)
+				f(2)
+				var r = [f(3), log]
+				r`,
+			want: []any{nil, []any{int64(1)}},
+		},
+		{
+			name: "returned falsy values",
+			in: `fn no() { return false }
+				fn zero() { return 0 }
+				fn empty() { return "" }
+				fn after() { if no() { return 1 } return 2 }
+				var r = [no(), zero(), empty(), after()]
+				r`,
+			want: []any{false, int64(0), "", int64(2)},
+		},
+		{
+			name: "loop value is not returned",
+			in:   "fn f() { var i = 0\nwhile i < 3 { i += 1\ni * 10 }\nfor _, v in [1] { v } }\nf()",
+			want: nil,
+		},
+		{
+			name: "return from deep inside loops",
+			in:   "fn find(rows, x) { for i, row in rows { for _, v in row { if v == x { return i } } }\nreturn -1 }\nvar r = [find([[1], [2, 3]], 3), find([[1]], 5)]\nr",
+			want: []any{int64(1), int64(-1)},
+		},
+		{
+			name: "bare return in a loop",
+			in:   "var n = 0\nfn f() { while true { n += 1\nif n == 3 { return } } }\nf()\nn",
+			want: int64(3),
+		},
+		{
+			name: "compound assignment",
+			in:   "var a = 10\nvar s = \"a\"\na += 5\na -= 3\na *= 2\na /= 4\ns += \"b\"\nvar r = [a, s]\nr",
+			want: []any{int64(6), "ab"},
+		},
+		{
+			name: "compound assignment in a classic for",
+			in:   "var total = 0\nfor i = 0; i < 10; i += 3 { total += i }\ntotal",
+			want: int64(18),
+		},
+		{
+			name: "compound assignment to an outer variable",
+			in:   "var n = 1\nfn double() { n *= 2 }\nif true { n += 1 }\ndouble()\nn",
+			want: int64(4),
+		},
+		{
+			name: "compound assignment to an undefined variable",
+			in:   "x += 1",
+			want: NewRuntimeError("undefined variable x"),
+		},
+		{
+			name: "compound assignment with a type error",
+			in:   "var a = \"x\"\na -= 1",
+			want: NewRuntimeError("cannot subtract string and int"),
+		},
+		{
+			name: "add a bool and an int",
+			in:   "true + 1",
+			want: NewRuntimeError("cannot add bool and int"),
+		},
+		{
+			name: "operator that doesn't apply",
+			in:   `var r = ["a" * 2, "a" - "b", true < false, 1 and 2]` + "\nr",
+			want: NewRuntimeError("cannot multiply string and int"),
+		},
+		{
+			name: "and of ints",
+			in:   "1 and 2",
+			want: NewRuntimeError("cannot apply and to int and int"),
+		},
+		{
+			name: "local variable shadows an outer function",
+			in:   "fn f(x) {\n return 0\n}\nfn outer(n) {\n var f = fn(x) { return x * 8 }\n var g = f\n return f(n) + g(1)\n}\nouter(5)",
+			want: int64(48),
+		},
+		{
+			name: "local function shadows an outer variable",
+			in:   "var g = 1\nfn h() {\n fn g() { return 2 }\n return [g(), type(g)]\n}\nvar r = [h(), g]\nr",
+			want: []any{[]any{int64(2), "function"}, int64(1)},
+		},
+		{
+			name: "bare return before a line",
+			in:   "var x = 1\nfn f() {\n return\n x = 2\n}\nvar r = [f(), x]\nr",
+			want: []any{nil, int64(1)},
+		},
+		{
+			name: "return continued on the next line",
+			in:   "var x = 1\nfn f() {\n return \\\n x + 1\n}\nf()",
+			want: int64(2),
+		},
+		{
+			name: "suffixes after literals",
+			in:   "var x = [1, 2, 3][1]\nvar r = [x, \"ab\"[0], (fn(x) { return x * 2 })(5), {\"k\": [7]}[\"k\"][0]]\nr",
+			want: []any{int64(2), "a", int64(10), int64(7)},
+		},
+		{
+			name: "error in a print separator",
+			in:   "print(1, 2, sep=1/0)",
+			want: NewRuntimeError("division by zero"),
+		},
+		{
+			name: "print separator that isn't a string",
+			in:   "var s = sprint(1, 2, sep=1)",
+			want: NewRuntimeError("sep must be a string, got int"),
+		},
+		{
+			name: "print end that isn't a string",
+			in:   "println(1, end=[\"\\n\"])",
+			want: NewRuntimeError("end must be a string, got array"),
+		},
+		{
+			name: "len of a number",
+			in:   "var a = len(5)",
+			want: NewRuntimeError("len expects a string, an array, or a map, got int"),
+		},
+		{
+			name: "too few arguments",
+			in:   "fn f(a, b) {}\nf(1)",
+			want: NewRuntimeError("f expects 2 arguments, got 1"),
+		},
+		{
+			name: "too few spread arguments",
+			in:   "fn f(a, b) {}\nf(...[1])",
+			want: NewRuntimeError("f expects 2 arguments, got 1"),
+		},
+		{
+			name: "too many arguments to a function value",
+			in:   "var g = fn(x) { return x }\ng(1, 2)",
+			want: NewRuntimeError("function expects 1 argument, got 2"),
+		},
+		{
+			name: "sorted mixed types",
+			in:   `var a = sorted([1, "a"])`,
+			want: NewRuntimeError("cannot compare string and int"),
+		},
+		{
+			name: "assert without arguments",
+			in:   "assert()",
+			want: NewRuntimeError("assert expects 1 or 2 arguments, got 0"),
+		},
+		{
+			name: "assert_eq with one argument",
+			in:   "assert_eq(1)",
+			want: NewRuntimeError("assert_eq expects 2 arguments, got 1"),
+		},
+		{
+			name: "sorted without arguments",
+			in:   "var a = sorted()",
+			want: NewRuntimeError("sorted expects 1 argument, got 0"),
+		},
+		{
+			name: "sorted a number",
+			in:   "var a = sorted(5)",
+			want: NewRuntimeError("sorted expects an array or a map, got int"),
+		},
+		{
+			name: "reversed a number",
+			in:   "var a = reversed(5)",
+			want: NewRuntimeError("reversed expects an array or a string, got int"),
+		},
+		{
+			name: "assign an undeclared variable",
+			in:   "x = 5",
+			want: NewRuntimeError("undefined variable x"),
+		},
+		{
+			name: "negate a string",
+			in:   `var s = "a"` + "\nvar t = -s",
+			want: NewRuntimeError("cannot apply - to string"),
+		},
+		{
+			name: "chained unary operators",
+			in:   "[!!true, --5, -+-2.5, !(1 == 2), !true == false]",
+			want: []any{true, int64(5), 2.5, true, true},
+		},
+		{
+			name: "not of a non-bool",
+			in:   "var a = !1",
+			want: NewRuntimeError("cannot apply ! to int"),
+		},
+		{
+			name: "plus of a non-number",
+			in:   "var a = +[1]",
+			want: NewRuntimeError("cannot apply + to array"),
+		},
+		{
+			name: "error in an operand",
+			in:   "var a = [1]\n(1 / 0) + a[\"x\"]",
+			want: NewRuntimeError("division by zero"),
+		},
+		{
+			name: "index a non-array",
+			in:   "var n = 1\nn[0]",
+			want: NewRuntimeError("cannot index int"),
+		},
+		{
+			name: "array index that isn't an int",
+			in:   "var a = [1]\na[\"0\"]",
+			want: NewRuntimeError("array index must be an int, got string"),
+		},
+		{
+			name: "call a non-function",
+			in:   "var n = 1\nn()",
+			want: NewRuntimeError("cannot call int"),
+		},
+		{
+			name: "if condition that isn't a bool",
+			in:   "if 1 { 2 }",
+			want: NewRuntimeError("if condition must be a bool, got int"),
+		},
+		{
+			name: "while condition that isn't a bool",
+			in:   `while "yes" {}`,
+			want: NewRuntimeError("while condition must be a bool, got string"),
+		},
+		{
+			name: "for over an int",
+			in:   "for i, v in 3 {}",
+			want: NewRuntimeError("cannot iterate over int"),
+		},
+		{
+			name: "error stops the program",
+			in:   "var a = 1\na = true + 1\na = 5\na",
+			want: NewRuntimeError("cannot add bool and int"),
+		},
+		{
+			name: "slice an array",
+			in:   "var a = [1, 2, 3, 4]\nvar r = [a[1:3], a[:2], a[2:], a[:], a[4:]]\nr",
+			want: []any{[]any{int64(2), int64(3)}, []any{int64(1), int64(2)}, []any{int64(3), int64(4)}, []any{int64(1), int64(2), int64(3), int64(4)}, []any{}},
+		},
+		{
+			name: "slice is a copy",
+			in:   "var a = [1, 2]\nvar b = a[:]\nb[0] = 5\na",
+			want: []any{int64(1), int64(2)},
+		},
+		{
+			name: "slice a string",
+			in:   `var s = "héllo"` + "\n" + `[s[1:3], s[:1], s[3:], len(s)]`,
+			want: []any{"él", "h", "lo", int64(5)},
+		},
+		{
+			name: "slice out of range",
+			in:   "var s = \"abc\"\ns[1:4]",
+			want: NewRuntimeError("slice bounds [1:4] out of range for length 3"),
+		},
+		{
+			name: "slice with reversed bounds",
+			in:   "var a = [1, 2, 3]\na[2:1]",
+			want: NewRuntimeError("slice bounds [2:1] out of range for length 3"),
+		},
+		{
+			name: "slice with a float bound",
+			in:   "var a = [1, 2, 3]\na[0:1.5]",
+			want: NewRuntimeError("slice bounds must be ints, got float"),
+		},
+		{
+			name: "slice a map",
+			in:   "var m = {\"a\": 1}\nm[0:1]",
+			want: NewRuntimeError("cannot slice map"),
+		},
+		{
+			name: "array containing itself",
+			in:   "var a = [1, 2]\na[1] = a\nstr(a)",
+			want: "[1, [...]]",
+		},
+		{
+			name: "break in while",
+			in:   "var n = 0\nwhile true {\nn = n + 1\nif n == 3 { break }\n}\nn",
+			want: int64(3),
+		},
+		{
+			name: "continue in for",
+			in:   "var odd = 0\nfor _, v in [1, 2, 3, 4, 5] {\nif v % 2 == 0 { continue }\nodd = odd + v\n}\nodd",
+			want: int64(9),
+		},
+		{
+			name: "break only stops the innermost loop",
+			in:   "var count = 0\nfor _, a in [1, 2, 3] {\nfor _, b in [1, 2, 3] {\nif b == 2 { break }\ncount = count + 1\n}\n}\ncount",
+			want: int64(3),
+		},
+		{
+			name: "break inside if and typematch",
+			in:   "var n = 0\nwhile true {\nn = n + 1\ntypematch n { int: { if n > 4 { break } } }\n}\nn",
+			want: int64(5),
+		},
+		{
+			name: "continue in classic for runs the post step",
+			in:   "var sum = 0\nfor i = 0; i < 5; i = i + 1 {\nif i == 2 { continue }\nsum = sum + i\n}\nsum",
+			want: int64(8),
+		},
+		{
+			name: "len is an int",
+			in:   `[len("abc") + 1, len([1]) * 2, len({}) - 1]`,
+			want: []any{int64(4), int64(2), int64(-1)},
+		},
+		{
+			name: "for keys are ints",
+			in:   "var keys = []\nfor k, _ in [\"a\", \"b\"] { keys = [...keys, k * 10] }\nfor k, _ in \"ab\" { keys = [...keys, k + 1] }\nkeys",
+			want: []any{int64(0), int64(10), int64(1), int64(2)},
+		},
+		{
+			name: "range iteration",
+			in:   "var sum = 0\nfor i in 0..10 { sum = sum + i }\nsum",
+			want: int64(45),
+		},
+		{
+			name: "range iteration with an index",
+			in:   "var pairs = []\nfor i, n in 5..8 { pairs = [...pairs, [i, n]] }\npairs",
+			want: []any{[]any{int64(0), int64(5)}, []any{int64(1), int64(6)}, []any{int64(2), int64(7)}},
+		},
+		{
+			name: "empty range",
+			in:   "var count = 0\nfor i in 3..3 { count = count + 1 }\nfor i in 3..0 { count = count + 1 }\n[count, 3..0]",
+			want: []any{int64(0), []any{}},
+		},
+		{
+			name: "range as an array",
+			in:   "var n = 2\nvar r = -1..n + 1\nr",
+			want: []any{int64(-1), int64(0), int64(1), int64(2)},
+		},
+		{
+			name: "range with float bounds",
+			in:   "0..1.5",
+			want: NewRuntimeError("range bounds must be ints, got int and float"),
+		},
+		{
+			name: "classic for",
+			in:   "var sum = 0\nfor i = 0; i < 10; i = i + 1 { sum = sum + i }\nsum",
+			want: int64(45),
+		},
+		{
+			name: "descending classic for over an array",
+			in:   "var items = [\"a\", \"b\", \"c\"]\nvar order = \"\"\nfor i = len(items) - 1; i >= 0; i = i - 1 { order = order + items[i] }\norder",
+			want: "cba",
+		},
+		{
+			name: "nested classic for",
+			in:   "var count = 0\nfor var i = 0; i < 3; i = i + 1 {\nfor j = i; j < 3; j = j + 1 { count = count + 1 }\n}\ncount",
+			want: int64(6),
+		},
+		{
+			name: "classic for with a condition that isn't a bool",
+			in:   "for i = 0; i; i = i + 1 {}",
+			want: NewRuntimeError("for condition must be a bool, got int"),
+		},
+		{
+			name: "return from classic for",
+			in:   "fn first(n) {\nfor ; ; n = n + 1 {\nif n > 5 { return n }\n}\n}\nfirst(0)",
+			want: int64(6),
+		},
+		{
+			name: "labeled break out of nested loops",
+			in:   "var pairs = \"\"\nouter: for _, a in [1, 2, 3] {\nfor _, b in [1, 2, 3] {\nif a * b == 4 { break outer }\npairs = pairs + str(a) + str(b) + \" \"\n}\n}\npairs",
+			want: "11 12 13 21 ",
+		},
+		{
+			name: "labeled continue",
+			in:   "var count = 0\nrows: for i = 0; i < 3; i = i + 1 {\nvar j = 0\nwhile true {\nj = j + 1\nif j > i { continue rows }\ncount = count + 1\n}\n}\ncount",
+			want: int64(3),
+		},
+		{
+			name: "string comparisons",
+			in:   `["abc" < "abd", "b" > "abc", "a" <= "a", "a" >= "b", "uni" == "uni", "uni" != "Uni", "" < "a"]`,
+			want: []any{true, true, true, false, true, true, true},
+		},
+		{
+			name: "int modulo",
+			in:   "[7 % 3, -7 % 3]",
+			want: []any{int64(1), int64(-1)},
+		},
+		{
+			name: "float modulo",
+			in:   "[7.5 % 2, 7 % 2.5, 7.5 % 2.5]",
+			want: []any{1.5, 2.0, 0.0},
+		},
+		{
+			name: "division by zero",
+			in:   "var a = 5 / 0",
+			want: NewRuntimeError("division by zero"),
+		},
+		{
+			name: "float division by zero",
+			in:   "[1 / 0.0, -1.0 / 0]",
+			want: []any{math.Inf(1), math.Inf(-1)},
+		},
+		{
+			name: "modulo by zero",
+			in:   "var a = 1 % 0",
+			want: NewRuntimeError("modulo by zero"),
+		},
+		{
+			name: "recursion below the depth limit",
+			in:   "fn depth(n) {\n if n == 0 {\n return 0\n }\n return 1 + depth(n - 1)\n}\ndepth(9000)",
+			want: int64(9000),
+		},
+		{
+			name: "unbounded recursion",
+			in:   "fn forever(n) {\n return 1 + forever(n + 1)\n}\nvar a = forever(0)",
+			want: NewRuntimeError("maximum recursion depth exceeded"),
+		},
+		{
+			name: "bitwise operators",
+			in:   "[6 & 3, 6 | 3, 6 ^ 3, 1 << 4, -16 >> 2, 1 | 2 << 1]",
+			want: []any{int64(2), int64(7), int64(5), int64(16), int64(-4), int64(5)},
+		},
+		{
+			name: "bitwise operators on floats",
+			in:   "var a = 1.0 & 1",
+			want: NewRuntimeError("cannot apply & to float and int"),
+		},
+		{
+			name: "negative shift count",
+			in:   "var a = 1 << -1",
+			want: NewRuntimeError("negative shift count -1"),
+		},
+		{
+			name: "string escapes",
+			in:   `sprint("a\tb", "say \"hi\"\n")`,
+			want: "a\tb say \"hi\"\n",
+		},
+		{
+			name: "spread into an array",
+			in:   "var rest = [2, 3, 4]\n[1, ...rest, 5, ...[]]",
+			want: []any{int64(1), int64(2), int64(3), int64(4), int64(5)},
+		},
+		{
+			name: "spread into a call",
+			in:   "fn sum3(a, b, c) { return a + b + c }\nvar args = [2, 3]\nsum3(1, ...args) + gcd(...[12, 18])",
+			want: int64(12),
+		},
+		{
+			name: "spread of a non-array",
+			in:   "[...1]",
+			want: NewRuntimeError("cannot spread int, only an array"),
+		},
+		{
+			name: "string iteration by rune",
+			in:   "var seen = \"\"\nfor k, v in \"héy\" { seen = seen + str(k) + v }\nseen",
+			want: "0h1é2y",
+		},
+		{
+			name: "reversed array iteration",
+			in:   "var order = \"\"\nfor _, v in reversed([1, 2, 3]) { order = order + str(v) }\norder",
+			want: "321",
+		},
+		{
+			name: "reversed string",
+			in:   `reversed("héllo")`,
+			want: "olléh",
+		},
+		{
+			name: "reversed empty array",
+			in:   `reversed([])`,
+			want: []any{},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			lexer := NewLexer(tc.in)
+			parser := NewParser(lexer)
+			evaluator := NewEvaluator(parser)
+			got := evaluator.Eval(NewScope(nil))
+			if err, ok := got.(RuntimeError); ok {
+				err.Line, err.Column = 0, 0 // positions are tested on their own
+				got = err
+			}
+			assert.Equal(t, got, tc.want)
+		})
+	}
+}
+
+func TestRuntimeErrorPosition(t *testing.T) {
+	tt := []struct {
+		in   string
+		want string
+	}{
+		{in: "true + 1", want: "1:6: runtime error: cannot add bool and int"},
+		{in: "var a = 1\nvar b = a / (a - 1)", want: "2:11: runtime error: division by zero"},
+		{in: "fn f(x) {\n  return x + true\n}\nf(1)", want: "2:12: runtime error: cannot add int and bool"},
+		{in: "var a = [1]\n  a[3] = 1", want: "2:3: runtime error: index 3 out of range for array of length 1"},
+		{in: "var n = 1\nn()", want: "2:1: runtime error: cannot call int"},
+		{in: "var n = 1\nif n { }", want: "2:4: runtime error: if condition must be a bool, got int"},
+		{in: "if 1 { }", want: "runtime error: if condition must be a bool, got int"},
+		{in: "var a = [1]\nvar b = [0, ...a[0]]", want: "2:13: runtime error: cannot spread int, only an array"},
+	}
+	for _, tc := range tt {
+		evaluator := NewEvaluator(NewParser(NewLexer(tc.in)))
+		evaluator.SetErrorOutput(io.Discard)
+		got, ok := evaluator.Eval(NewScope(nil)).(error)
+		if assert.True(t, ok, tc.in) {
+			assert.EqualError(t, got, tc.want, tc.in)
+		}
+	}
+}
+
+func TestEvalWithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+	}()
+	lexer := NewLexer("var i = 0\nwhile true { i = i + 1 }\ni")
+	parser := NewParser(lexer)
+	evaluator := NewEvaluator(parser)
+	got := evaluator.EvalWithContext(ctx, NewScope(nil))
+	assert.Equal(t, context.Canceled, got)
+
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	evaluator = NewEvaluator(NewParser(NewLexer("for i in 0..1000000000 {}")))
+	evaluator.SetErrorOutput(io.Discard)
+	assert.Equal(t, context.DeadlineExceeded, evaluator.EvalWithContext(ctx, NewScope(nil)))
+
+	// a block stops between its statements, even without a loop around it
+	ctx, cancel = context.WithCancel(context.Background())
+	scope := NewScope(nil)
+	scope.RegisterNative("cancel", func(args []any) (any, error) {
+		cancel()
+		return nil, nil
+	})
+	out := &bytes.Buffer{}
+	evaluator = NewEvaluator(NewParser(NewLexer("fn f() {\n cancel()\n println(\"after\")\n}\nf()")))
+	evaluator.SetOutput(out)
+	evaluator.SetErrorOutput(io.Discard)
+	assert.Equal(t, context.Canceled, evaluator.EvalWithContext(ctx, scope))
+	assert.Empty(t, out.String())
+
+	// nor does a typematch pick a case for the subject it was stopped in
+	ctx, cancel = context.WithCancel(context.Background())
+	scope = NewScope(nil)
+	scope.RegisterNative("cancel", func(args []any) (any, error) {
+		cancel()
+		return nil, nil
+	})
+	out.Reset()
+	evaluator = NewEvaluator(NewParser(NewLexer("fn f() {\n cancel()\n return 1\n}\ntypematch f() { else: { println(\"matched\") } }")))
+	evaluator.SetOutput(out)
+	evaluator.SetErrorOutput(io.Discard)
+	assert.Equal(t, context.Canceled, evaluator.EvalWithContext(ctx, scope))
+	assert.Empty(t, out.String())
+}
+
+func TestPrint(t *testing.T) {
+	tt := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			name: "print",
+			in:   `print("a", 1, 2.5, true)`,
+			want: "a 1 2.5 true",
+		},
+		{
+			name: "println",
+			in:   `println("a", "b") println()`,
+			want: "a b\n\n",
+		},
+		{
+			name: "separator",
+			in:   `println(1, 2, 3, sep=", ")`,
+			want: "1, 2, 3\n",
+		},
+		{
+			name: "end",
+			in:   `print("a", end="!") print("b", end="")`,
+			want: "a!b",
+		},
+		{
+			name: "throwaway for key",
+			in:   `for _, v in ["a", "b"] { print(v) }`,
+			want: "ab",
+		},
+		{
+			name: "throwaway is never bound",
+			in:   `var _ = 1 _ = 2 for _ in [1, 2] { print(".") }`,
+			want: "..",
+		},
+		{
+			name: "containers",
+			in:   `fn nothing() {}` + "\n" + `println([1, 2.5, "a"], {"k": [true], 1: nothing()}, 1.0 + 0.5)`,
+			want: "[1, 2.5, \"a\"] {1: nil, \"k\": [true]} 1.5\n",
+		},
+		{
+			name: "separator and end",
+			in:   `var s = "-" println("x", "y", end=".", sep=s + s)`,
+			want: "x--y.",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			lexer := NewLexer(tc.in)
+			parser := NewParser(lexer)
+			evaluator := NewEvaluator(parser)
+			evaluator.SetOutput(out)
+			evaluator.Eval(NewScope(nil))
+			assert.Equal(t, tc.want, out.String())
+		})
+	}
+}
+
+func TestInput(t *testing.T) {
+	out := &bytes.Buffer{}
+	evaluator := NewEvaluator(NewParser(NewLexer(`
+		var name = input("name? ")
+		var second = input()
+		var r = [name, second, input(), input()]
+		r
+	`)))
+	evaluator.SetInput(strings.NewReader("ada\r\nbob\nlast"))
+	evaluator.SetOutput(out)
+	assert.Equal(t, []any{"ada", "bob", "last", nil}, evaluator.Eval(NewScope(nil)))
+	assert.Equal(t, "name? ", out.String())
+
+	evaluator = NewEvaluator(NewParser(NewLexer(`input(">", ">")`)))
+	evaluator.SetErrorOutput(io.Discard)
+	assert.EqualError(t, evaluator.Eval(NewScope(nil)).(error), "1:1: runtime error: input expects at most 1 argument, got 2")
+}
+
+func TestRegisterNative(t *testing.T) {
+	scope := NewScope(nil)
+	var got []any
+	scope.RegisterNative("record", func(args []any) (any, error) {
+		got = args
+		return []int{len(args)}, nil
+	})
+	scope.RegisterNative("fail", func(args []any) (any, error) {
+		return nil, errors.New("out of luck")
+	})
+	evaluator := NewEvaluator(NewParser(NewLexer(`
+		var r = [record(1, "a", [true]), type(record), str(record), partial(record, 0)(1)]
+		r
+	`)))
+	assert.Equal(t, []any{[]any{int64(3)}, "function", "native record", []any{int64(2)}}, evaluator.Eval(scope))
+	assert.Equal(t, []any{int64(0), int64(1)}, got)
+
+	evaluator = NewEvaluator(NewParser(NewLexer("fail()")))
+	evaluator.SetErrorOutput(io.Discard)
+	assert.EqualError(t, evaluator.Eval(scope).(error), "1:1: runtime error: fail: out of luck")
+}
+
+func TestCallFunction(t *testing.T) {
+	evaluator := NewEvaluator(NewParser(NewLexer("")))
+	assert.Equal(t, NewRuntimeError("cannot call int"), evaluator.callFunction(int64(1), nil, NewScope(nil)))
+}
+
+func TestSprint(t *testing.T) {
+	tt := []struct {
+		name   string
+		sprint string
+		print  string
+	}{
+		{
+			name:   "sprint",
+			sprint: `sprint("a", 1, 2.5, true)`,
+			print:  `print("a", 1, 2.5, true)`,
+		},
+		{
+			name:   "sprintln",
+			sprint: `sprintln("a", "b")`,
+			print:  `println("a", "b")`,
+		},
+		{
+			name:   "separator and end",
+			sprint: `sprintln(1, 2, sep=", ", end="!")`,
+			print:  `println(1, 2, sep=", ", end="!")`,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			evaluator := NewEvaluator(NewParser(NewLexer(tc.print)))
+			evaluator.SetOutput(out)
+			assert.Nil(t, evaluator.Eval(NewScope(nil)))
+
+			evaluator = NewEvaluator(NewParser(NewLexer(tc.sprint)))
+			evaluator.SetOutput(out)
+			got := evaluator.Eval(NewScope(nil))
+			assert.Equal(t, out.String(), got)
+		})
+	}
+}
+
+func TestTailCalls(t *testing.T) {
+	in := `fn sum(n, acc) {
+			if n == 0 {
+				return acc
+			}
+			return sum(n - 1, acc + n)
+		}
+		sum(%d, 0)
+	`
+	evaluator := NewEvaluator(NewParser(NewLexer(fmt.Sprintf(in, 1000000))))
+	assert.Equal(t, int64(500000500000), evaluator.Eval(NewScope(nil)))
+
+	evaluator = NewEvaluator(NewParser(NewLexer(fmt.Sprintf(in, 100))))
+	evaluator.SetTailCalls(false)
+	assert.Equal(t, int64(5050), evaluator.Eval(NewScope(nil)))
+
+	// a nested function with the same name is a different function, not a self call
+	shadowed := "fn f(n) {\n fn f(m) {\n return m + 100\n }\n return f(n)\n}\nf(1)"
+	for _, enabled := range []bool{true, false} {
+		evaluator = NewEvaluator(NewParser(NewLexer(shadowed)))
+		evaluator.SetTailCalls(enabled)
+		assert.Equal(t, int64(101), evaluator.Eval(NewScope(nil)))
+	}
+
+	// the next call sees the variables of the call it replaces, as it would without the
+	// optimization
+	dynamic := "fn f(n) {\n if n == 0 { return x }\n var x = n\n return f(n - 1)\n}\nf(1)"
+	for _, enabled := range []bool{true, false} {
+		evaluator = NewEvaluator(NewParser(NewLexer(dynamic)))
+		evaluator.SetTailCalls(enabled)
+		assert.Equal(t, int64(1), evaluator.Eval(NewScope(nil)), enabled)
+	}
+}
+
+func TestProfile(t *testing.T) {
+	in := `fn double(n) {
+			return n * 2
+		}
+		for i, v in [1, 2, 3] {
+			var d = double(v)
+		}
+		double(3)`
+	profile := NewProfile()
+	evaluator := NewEvaluator(NewParser(NewLexer(in)))
+	evaluator.SetProfile(profile)
+	assert.Equal(t, int64(6), evaluator.Eval(NewScope(nil)))
+	assert.Equal(t, 1, profile.Counts["For"])
+	assert.Equal(t, 4, profile.Counts["Call"])
+	assert.Equal(t, 4, profile.Counts["BinaryOperation"])
+	assert.NotZero(t, profile.Counts["Identifier"])
+	assert.NotZero(t, profile.Durations["For"])
+
+	out := &bytes.Buffer{}
+	profile.Report(out)
+	assert.Contains(t, out.String(), "For")
+}
+
+func TestInspect(t *testing.T) {
+	tt := []struct {
+		in   any
+		want string
+	}{
+		{in: int64(1000000), want: "1000000"},
+		{in: int64(-42), want: "-42"},
+		{in: 0.1, want: "0.1"},
+		{in: 2.5, want: "2.5"},
+		{in: 1e20, want: "100000000000000000000"},
+		{in: 1e21, want: "1e+21"},
+		{in: 1e-7, want: "1e-07"},
+		{in: 0.0, want: "0"},
+		{in: "abc", want: "abc"},
+		{in: true, want: "true"},
+		{in: nil, want: "nil"},
+		{in: []any{int64(1), []any{int64(2), 0.5}, map[any]any{"k": "v"}}, want: `[1, [2, 0.5], {"k": "v"}]`},
+		{in: map[any]any{"b": []any{}, "a": map[any]any{}, int64(1): true}, want: `{1: true, "a": {}, "b": []}`},
+	}
+	for _, tc := range tt {
+		t.Run(tc.want, func(t *testing.T) {
+			assert.Equal(t, tc.want, Inspect(tc.in))
+		})
+	}
+
+	self := map[any]any{"n": int64(1)}
+	self["self"] = self
+	assert.Equal(t, `{"n": 1, "self": {...}}`, Inspect(self))
+	array := []any{int64(1), nil}
+	array[1] = array
+	assert.Equal(t, "[1, [...]]", Inspect(array))
+	indirect := map[any]any{}
+	indirect["items"] = []any{indirect}
+	assert.Equal(t, `{"items": [{...}]}`, Inspect(indirect))
+	shared := []any{int64(1)}
+	assert.Equal(t, "[[1], [1]]", Inspect([]any{shared, shared}))
+
+	scope := NewScope(nil)
+	scope.SetVariable(Identifier{Token: NewToken(IDENT, "m")}, self)
+	evaluator := NewEvaluator(NewParser(NewLexer(`str(m)`)))
+	assert.Equal(t, `{"n": 1, "self": {...}}`, evaluator.Eval(scope))
+}
+
+func TestErrorOutput(t *testing.T) {
+	out, errOut := &bytes.Buffer{}, &bytes.Buffer{}
+	evaluator := NewEvaluator(NewParser(NewLexer("println(\"before\")\nassert(1 > 2)\nprintln(\"after\")")))
+	evaluator.SetOutput(out)
+	evaluator.SetErrorOutput(errOut)
+	assert.IsType(t, RuntimeError{}, evaluator.Eval(NewScope(nil)))
+	assert.Equal(t, "before\n", out.String())
+	assert.Equal(t, "2:1: runtime error: assertion failed\n", errOut.String())
+
+	errOut.Reset()
+	evaluator = NewEvaluator(NewParser(NewLexer("1 + )")))
+	evaluator.SetErrorOutput(errOut)
+	assert.Error(t, evaluator.Eval(NewScope(nil)).(error))
+	assert.NotEmpty(t, errOut.String())
+}
+
+func TestDefer(t *testing.T) {
+	out := &bytes.Buffer{}
+	evaluator := NewEvaluator(NewParser(NewLexer(`
+		fn work(n) {
+			defer println("first deferred")
+			defer println("second deferred, n is", n)
+			println("body")
+			n = n + 1
+			return n * 10
+		}
+		println("result", work(1))
+		fn failing() {
+			defer assert(false, "in defer")
+			return 1
+		}
+		failing()
+	`)))
+	evaluator.SetOutput(out)
+	evaluator.SetErrorOutput(io.Discard)
+	assert.Equal(t, RuntimeError{Message: "assertion failed: in defer", Line: 11, Column: 10}, evaluator.Eval(NewScope(nil)))
+	assert.Equal(t, "body\nsecond deferred, n is 2\nfirst deferred\nresult 20\n", out.String())
+}
+
+func TestPrecision(t *testing.T) {
+	out := &bytes.Buffer{}
+	evaluator := NewEvaluator(NewParser(NewLexer(`
+		var a = 0.1 + 0.2
+		println(a, [2.0 / 3.0], str(a))
+		a
+	`)))
+	evaluator.SetOutput(out)
+	evaluator.SetPrecision(4)
+	sum := 0.1
+	sum += 0.2
+	assert.Equal(t, sum, evaluator.Eval(NewScope(nil)))
+	assert.Equal(t, "0.3 [0.6667] 0.3\n", out.String())
+
+	assert.Equal(t, "0.30000000000000004", Inspect(sum))
+	assert.Equal(t, "123500", InspectPrecision(123456.0, 4))
+	assert.Equal(t, "7", InspectPrecision(int64(7), 1))
+}
+
+func TestFreeze(t *testing.T) {
+	scope := NewScope(nil)
+	NewEvaluator(NewParser(NewLexer(`var a = freeze([1, 2])`))).Eval(scope)
+	got := NewEvaluator(NewParser(NewLexer(`is_frozen(a)`))).Eval(NewScope(scope))
+	assert.Equal(t, true, got)
+
+	items := []any{int64(1), map[any]any{"k": []any{}}}
+	assert.False(t, scope.IsFrozen(items))
+	NewScope(scope).Freeze(items)
+	assert.True(t, scope.IsFrozen(items))
+	assert.True(t, scope.IsFrozen(items[1]))
+	assert.False(t, scope.IsFrozen(int64(1)))
+
+	// frozen containers stay alive, so a new one can't be mistaken for a collected one
+	for i := 0; i < 1000; i++ {
+		scope.Freeze([]any{int64(i)})
+		scope.Freeze(map[any]any{int64(i): true})
+	}
+	runtime.GC()
+	for i := 0; i < 1000; i++ {
+		assert.False(t, scope.IsFrozen([]any{int64(i)}))
+		assert.False(t, scope.IsFrozen(map[any]any{int64(i): true}))
+	}
+}
+
+func TestSetExistingVariable(t *testing.T) {
+	name := Identifier{Token: NewToken(IDENT, "a")}
+	outer := NewScope(nil)
+	outer.SetVariable(name, int64(1))
+	middle := NewScope(outer)
+	middle.SetVariable(name, int64(2))
+	inner := NewScope(middle)
+
+	assert.True(t, inner.SetExistingVariable(name, int64(3)))
+	got, _ := middle.GetVariable(name)
+	assert.Equal(t, int64(3), got)
+	got, _ = outer.GetVariable(name)
+	assert.Equal(t, int64(1), got)
+	_, ok := inner.variables["a"]
+	assert.False(t, ok)
+
+	assert.False(t, inner.SetExistingVariable(Identifier{Token: NewToken(IDENT, "b")}, int64(1)))
+	_, ok = inner.GetVariable(Identifier{Token: NewToken(IDENT, "b")})
+	assert.False(t, ok)
+}
+
+// run evaluates the source code in a scope of its own, with the sandbox on or off.
+func run(in string, sandbox bool) any {
+	evaluator := NewEvaluator(NewParser(NewLexer(in)))
+	evaluator.SetErrorOutput(io.Discard)
+	evaluator.SetSandbox(sandbox)
+	return evaluator.Eval(NewScope(nil))
+}
+
+func TestHTTPGet(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, "hello from "+r.URL.Path)
+	}))
+	defer server.Close()
+
+	assert.Equal(t, "hello from /uni", run(`http_get("`+server.URL+`/uni")`, false))
+	assert.EqualError(t, run(`http_get("`+server.URL+`/missing")`, false).(error), "1:1: runtime error: http_get: "+server.URL+"/missing returned 404 Not Found")
+	assert.EqualError(t, run(`http_get("`+server.URL+`/uni")`, true).(error), "1:1: runtime error: http_get is disabled in the sandbox")
+	assert.IsType(t, RuntimeError{}, run(`http_get("http://127.0.0.1:0/")`, false))
+}
+
+func TestSandbox(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "note.txt")
+	t.Setenv("UNI_TEST_ENV", "set")
+
+	in := fmt.Sprintf(`write_file(%q, "hi")
+		var r = [read_file(%q), env("UNI_TEST_ENV"), env("UNI_TEST_UNSET")]
+		r`, path, path)
+	assert.Equal(t, []any{"hi", "set", nil}, run(in, false))
+
+	assert.EqualError(t, run(fmt.Sprintf("read_file(%q)", path), true).(error), "1:1: runtime error: read_file is disabled in the sandbox")
+	assert.EqualError(t, run(fmt.Sprintf("write_file(%q, %q)", path, "x"), true).(error), "1:1: runtime error: write_file is disabled in the sandbox")
+	assert.EqualError(t, run(`env("UNI_TEST_ENV")`, true).(error), "1:1: runtime error: env is disabled in the sandbox")
+	assert.Equal(t, []any{int64(6), "3"}, run("fn f(x) { return x * 2 }\nvar r = [f(3), str(3)]\nr", true))
+}
+
+func TestGoValues(t *testing.T) {
+	uni := map[any]any{
+		"name":  "uni",
+		"ports": []any{int64(80), int64(443)},
+		"ratio": 0.5,
+		"codes": map[any]any{int64(1): "one"},
+	}
+	goValue := map[string]any{
+		"name":  "uni",
+		"ports": []any{int64(80), int64(443)},
+		"ratio": 0.5,
+		"codes": map[any]any{int64(1): "one"},
+	}
+	assert.Equal(t, goValue, ToGo(uni))
+	assert.Equal(t, uni, FromGo(ToGo(uni)))
+
+	type point struct{ X int }
+	p := &point{X: 1}
+	assert.Equal(t, []any{int64(1), int64(2)}, FromGo([]int{1, 2}))
+	assert.Equal(t, map[any]any{"a": 1.5, "b": int64(7)}, FromGo(map[string]any{"a": float32(1.5), "b": uint8(7)}))
+	assert.Equal(t, []any{}, FromGo([]string(nil)))
+	assert.Equal(t, "x", FromGo(func() *string { s := "x"; return &s }()))
+	assert.Equal(t, *p, FromGo(p))
+	assert.Nil(t, FromGo((*point)(nil)))
+	assert.Equal(t, int64(3), FromGo(3))
+	assert.Equal(t, true, ToGo(true))
+}
@@ -0,0 +1,141 @@
+// Package uni is the Uni interpreter: a lexer, a parser, and an evaluator, wrapped in an
+// API for running Uni from Go. Run evaluates a program on its own, and an Interpreter
+// keeps its top-level scope between calls, so each call sees what the ones before it
+// defined, like the lines typed into the REPL.
+package uni
+
+import (
+	"bufio"
+	"context"
+	_ "embed"
+	"io"
+	"os"
+)
+
+//go:embed prelude.uni
+var prelude string
+
+// NewGlobalScope returns a top-level scope in which the prelude, the part of the standard
+// library written in Uni, is already defined.
+func NewGlobalScope() *Scope {
+	scope := NewScope(nil)
+	evaluator := NewEvaluatorFor(prelude, io.Discard)
+	if err, ok := evaluator.Eval(scope).(error); ok {
+		panic("prelude: " + err.Error())
+	}
+	return scope
+}
+
+// NewEvaluatorFor returns an evaluator for the source code, whose print and println write
+// to out.
+func NewEvaluatorFor(sourceCode string, out io.Writer) *Evaluator {
+	lexer := NewLexer(sourceCode)
+	parser := NewParser(lexer)
+	evaluator := NewEvaluator(parser)
+	evaluator.SetOutput(out)
+	return evaluator
+}
+
+// Interpreter evaluates source code against a top-level scope that lives as long as it
+// does.
+type Interpreter struct {
+	scope     *Scope
+	in        io.Reader
+	out       io.Writer
+	precision int
+	sandbox   bool
+	maxDepth  int
+}
+
+// Option configures an Interpreter.
+type Option func(*Interpreter)
+
+// WithOutput sets where print and println write to, which is stdout by default.
+func WithOutput(out io.Writer) Option {
+	return func(i *Interpreter) {
+		i.out = out
+	}
+}
+
+// WithInput sets where input reads lines from, which is stdin by default.
+func WithInput(in io.Reader) Option {
+	return func(i *Interpreter) {
+		// buffered once, so what one call reads ahead isn't lost to the next
+		i.in = bufio.NewReader(in)
+	}
+}
+
+// WithPrecision rounds the floats that print and str show, like Evaluator.SetPrecision.
+func WithPrecision(digits int) Option {
+	return func(i *Interpreter) {
+		i.precision = digits
+	}
+}
+
+// WithSandbox disables the builtins that reach files, the environment, or the network,
+// like Evaluator.SetSandbox.
+func WithSandbox() Option {
+	return func(i *Interpreter) {
+		i.sandbox = true
+	}
+}
+
+// WithMaxDepth sets how deeply functions can call each other, like Evaluator.SetMaxDepth.
+func WithMaxDepth(depth int) Option {
+	return func(i *Interpreter) {
+		i.maxDepth = depth
+	}
+}
+
+// WithVars defines the given Go values as variables of the top-level scope, converted
+// with FromGo.
+func WithVars(vars map[string]any) Option {
+	return func(i *Interpreter) {
+		i.scope.Inject(vars)
+	}
+}
+
+// NewInterpreter returns an interpreter whose top-level scope holds the prelude, and
+// whatever the options add to it.
+func NewInterpreter(opts ...Option) *Interpreter {
+	i := &Interpreter{scope: NewGlobalScope(), out: os.Stdout, maxDepth: DefaultMaxDepth}
+	for _, opt := range opts {
+		opt(i)
+	}
+	return i
+}
+
+// Eval evaluates the source code and returns the value of its last statement, or of its
+// top-level return. A syntax or runtime error is returned as the error, and the variables
+// defined before it stay defined.
+func (i *Interpreter) Eval(sourceCode string) (any, error) {
+	return i.EvalContext(context.Background(), sourceCode)
+}
+
+// EvalContext is Eval, stopped with the context's error when ctx is done, so a host can
+// give a script a deadline.
+func (i *Interpreter) EvalContext(ctx context.Context, sourceCode string) (any, error) {
+	evaluator := NewEvaluatorFor(sourceCode, i.out)
+	evaluator.SetErrorOutput(io.Discard) // returned instead
+	evaluator.SetPrecision(i.precision)
+	evaluator.SetSandbox(i.sandbox)
+	evaluator.SetMaxDepth(i.maxDepth)
+	if i.in != nil {
+		evaluator.SetInput(i.in)
+	}
+	result := evaluator.EvalWithContext(ctx, i.scope)
+	if err, ok := result.(error); ok {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Scope returns the top-level scope, in which Eval defines the variables and functions.
+func (i *Interpreter) Scope() *Scope {
+	return i.scope
+}
+
+// Run evaluates the source code in an interpreter of its own.
+func Run(sourceCode string, opts ...Option) (any, error) {
+	return NewInterpreter(opts...).Eval(sourceCode)
+}
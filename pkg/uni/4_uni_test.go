@@ -0,0 +1,122 @@
+package uni
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunWithVars(t *testing.T) {
+	vars := WithVars(map[string]any{
+		"config": map[string]any{"name": "uni", "retries": 3},
+		"limit":  2.5,
+	})
+	result, err := Run(`config["name"]`, vars)
+	assert.NoError(t, err)
+	assert.Equal(t, "uni", result)
+	result, err = Run(`config["retries"] + limit`, vars)
+	assert.NoError(t, err)
+	assert.Equal(t, 5.5, result)
+	_, err = Run(`missing`, vars)
+	assert.EqualError(t, err, "1:1: runtime error: undefined variable missing")
+}
+
+func TestPrelude(t *testing.T) {
+	vars := WithVars(map[string]any{"numbers": []any{1, 2, 3, 4}})
+	tt := []struct {
+		in   string
+		want any
+	}{
+		{in: "fn double(x) { return x * 2 }\nmap(double, numbers)", want: []any{int64(2), int64(4), int64(6), int64(8)}},
+		{in: "fn big(x) { return x > 2 }\nfilter(big, numbers)", want: []any{int64(3), int64(4)}},
+		{in: "fn add(a, b) { return a + b }\nreduce(add, numbers, 0)", want: int64(10)},
+		{in: "fn double(x) { return x * 2 }\nmap(double, [])", want: []any{}},
+	}
+	for _, tc := range tt {
+		result, err := Run(tc.in, vars)
+		assert.NoError(t, err, tc.in)
+		assert.Equal(t, tc.want, result, tc.in)
+	}
+}
+
+func TestRun(t *testing.T) {
+	out := &bytes.Buffer{}
+	result, err := Run("println(sqrt(2))\nmap(fn(x) { return x * limit }, [1, 2])", WithOutput(out), WithPrecision(3), WithVars(map[string]any{"limit": 2}))
+	assert.NoError(t, err)
+	assert.Equal(t, []any{int64(2), int64(4)}, result)
+	assert.Equal(t, "1.41\n", out.String())
+
+	result, err = Run("var a = 1\na / 0")
+	assert.Nil(t, result)
+	assert.EqualError(t, err, "2:3: runtime error: division by zero")
+
+	_, err = Run("1 + )")
+	assert.Error(t, err)
+
+	_, err = Run(`env("HOME")`, WithSandbox())
+	assert.EqualError(t, err, "1:1: runtime error: env is disabled in the sandbox")
+}
+
+func TestInterpreterStopsAtErrors(t *testing.T) {
+	interpreter := NewInterpreter()
+	eval := func(sourceCode string) any {
+		result, err := interpreter.Eval(sourceCode)
+		if err != nil {
+			return err
+		}
+		return result
+	}
+	assert.Equal(t, int64(3), eval("1 + 2"))
+	assert.Nil(t, eval("var a = 40"))
+	assert.Equal(t, int64(42), eval("a + 2"))
+	assert.EqualError(t, eval("if a = 40 { a = 0 }").(error), "1:6: unexpected = in condition, did you mean ==?")
+	assert.Equal(t, int64(40), eval("a"))
+	assert.EqualError(t, eval("assert_eq(a, 41)\na = 0").(error), "1:1: runtime error: assertion failed: 40 != 41")
+	assert.Equal(t, int64(40), eval("a"))
+}
+
+func TestInterpreter(t *testing.T) {
+	interpreter := NewInterpreter(WithInput(strings.NewReader("ada\nlovelace\n")))
+	_, err := interpreter.Eval("var first = input()\nfn greet(name) { return \"hi \" + name }")
+	assert.NoError(t, err)
+	_, err = interpreter.Eval("first = undefined_function()")
+	assert.Error(t, err)
+
+	result, err := interpreter.Eval("greet(first + \" \" + input())")
+	assert.NoError(t, err)
+	assert.Equal(t, "hi ada lovelace", result)
+	assert.Equal(t, map[string]any{"first": "ada"}, interpreter.Scope().Variables())
+}
+
+func TestMaxDepth(t *testing.T) {
+	interpreter := NewInterpreter(WithMaxDepth(10))
+	_, err := interpreter.Eval("fn down(n) {\n if n > 0 {\n down(n - 1)\n }\n}\ndown(9)")
+	assert.NoError(t, err)
+	_, err = interpreter.Eval("down(10)")
+	assert.EqualError(t, err, "3:2: runtime error: maximum recursion depth exceeded")
+	// the depth is back to 0 after the error, and a tail call doesn't add to it
+	_, err = interpreter.Eval("fn count(n) {\n if n == 0 {\n return 0\n }\n return count(n - 1)\n}\ncount(1000)")
+	assert.NoError(t, err)
+}
+
+func TestInterpreterTimeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := NewInterpreter().EvalContext(ctx, "while true {}")
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
+
+func ExampleScope_RegisterNative() {
+	interpreter := NewInterpreter()
+	interpreter.Scope().RegisterNative("now", func(args []any) (any, error) {
+		return time.Now().Unix(), nil
+	})
+	result, err := interpreter.Eval("now() > 0")
+	fmt.Println(result, err)
+	// Output: true <nil>
+}